@@ -38,6 +38,14 @@ func init() {
 }
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 && os.Args[1] == "timeshift" {
+		if err := runTimeShift(os.Args[2:]); err != nil {
+			log.Fatalf("修正拍攝時間失敗: %v", err)
+		}
+		return
+	}
+
 	// 解析命令列參數
 	flag.Parse()
 
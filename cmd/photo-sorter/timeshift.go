@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"photo-sorter/internal/app/photo-sorter/timeshift"
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+// runTimeShift timeshift 子命令：列出符合 time_corrections 的檔案與修正後的時間，
+// 加上 -write 時將修正後的時間寫回檔案
+func runTimeShift(args []string) error {
+	flags := flag.NewFlagSet("timeshift", flag.ExitOnError)
+	src := flags.String("src", ".", "原始照片資料夾")
+	configPath := flags.String("c", "config.yaml", "配置檔案路徑")
+	write := flags.Bool("write", false, "將修正後的時間寫回檔案")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("載入設定檔失敗: %v", err)
+	}
+	if *src != "." {
+		cfg.SrcDir = *src
+	}
+	if len(cfg.TimeCorrections) == 0 {
+		return errors.New("設定檔中沒有 time_corrections 規則")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	exifTool, err := exif.NewExifTool(ctx, 1)
+	if err != nil {
+		if *write {
+			return fmt.Errorf("寫回檔案需要 exiftool: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "無法使用 exiftool，只使用原生 EXIF 解析器: %v\n", err)
+	} else {
+		defer exifTool.Close()
+	}
+	dirs := exif.NewDirCache()
+	extractor, err := exif.NewExtractorChain(cfg, exifTool, dirs)
+	if err != nil {
+		return fmt.Errorf("建立中繼資料擷取器失敗: %v", err)
+	}
+
+	changes, err := timeshift.Find(ctx, cfg, extractor, dirs)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		fmt.Printf("%s: %s -> %s (%s)\n", change.Path,
+			change.Original.Format("2006-01-02 15:04:05"), change.Corrected.Format("2006-01-02 15:04:05"), change.Shift)
+	}
+	fmt.Printf("符合修正規則的檔案數: %d\n", len(changes))
+
+	if !*write {
+		fmt.Println("加上 -write 將修正後的時間寫回檔案")
+		return nil
+	}
+	if err := timeshift.Apply(ctx, changes); err != nil {
+		return err
+	}
+	// 寫回後檔案中的時間已修正，規則若仍保留，整理時會再修正一次
+	fmt.Println("已寫回檔案，請移除設定檔中對應的 time_corrections 規則，避免整理時重複修正")
+	return nil
+}
@@ -22,7 +22,7 @@ func main() {
 	}
 
 	// 比對目錄
-	result, err := verify.CompareDirectories(*sourceDir, *targetDir)
+	result, err := verify.CompareDirectories(*sourceDir, *targetDir, nil)
 	if err != nil {
 		fmt.Printf("錯誤：%v\n", err)
 		os.Exit(1)
@@ -10,12 +10,15 @@ import (
 	"time"
 
 	"photo-sorter/internal/app/photo-sorter/directory"
+	"photo-sorter/internal/app/photo-sorter/event"
 	"photo-sorter/internal/app/photo-sorter/file"
 	"photo-sorter/internal/app/photo-sorter/progress"
 	"photo-sorter/internal/app/photo-sorter/stats"
 	"photo-sorter/internal/app/photo-sorter/verify"
 	"photo-sorter/internal/app/photo-sorter/worker"
 	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
 	"photo-sorter/internal/pkg/logger"
 
 	"go.uber.org/zap"
@@ -27,6 +30,9 @@ type App struct {
 	logger    *logger.Logger
 	stats     *stats.Stats
 	progress  *progress.Progress
+	geocoder  geocoding.Geocoder         // 所有 worker 共用的地理編碼器，沒有用到地理位置時為 nil
+	timeZones geocoding.TimeZoneGeocoder // 所有 worker 共用的時區查詢器，未設定 timezone_json_path 時為 nil
+	dirs      *exif.DirCache             // 本次執行中共用的資料夾檔名快取，尋找 sidecar 與伴隨檔案時使用
 	startTime time.Time
 }
 
@@ -58,6 +64,9 @@ func (a *App) Run(ctx context.Context) error {
 		zap.Bool("是否啟用地理位置標籤", a.config.EnableGeoTag),
 		zap.String("地理編碼器類型", string(a.config.GeocoderType)),
 		zap.String("日誌等級", a.config.LogLevel),
+		zap.Int("exiftool 常駐程序數", a.config.ExifToolProcesses),
+		zap.Int("EXIF 批次大小", a.config.ExifBatchSize),
+		zap.Strings("中繼資料擷取器", a.config.MetadataExtractors),
 		zap.Bool("是否啟用驗證", a.config.EnableVerify),
 		zap.Any("忽略的檔案", a.config.Ignore),
 		zap.Any("支援的檔案格式", a.config.Formats),
@@ -75,13 +84,39 @@ func (a *App) Run(ctx context.Context) error {
 	// 記錄開始時間
 	startTime := time.Now()
 
-	// 建立工作通道
-	jobs := make(chan string, 100)
-	results := make(chan error, 100)
+	// 啟動 exiftool 常駐程序，由所有 worker 共用，只處理原生解析器無法處理的檔案
+	exifProcesses := a.config.ExifToolProcesses
+	if exifProcesses <= 0 {
+		exifProcesses = a.config.Workers
+	}
+	exifTool, err := exif.NewExifTool(ctx, exifProcesses)
+	if err != nil {
+		a.logger.LogWarn("無法使用 exiftool，只使用原生 EXIF 解析器", zap.Error(err))
+	} else {
+		defer exifTool.Close()
+	}
+
+	// 來源資料夾在執行期間不會變動，檔名快取只在這次執行中使用
+	a.dirs = exif.NewDirCache()
+
+	// 依設定的順序建立中繼資料擷取器串鏈
+	extractor, err := exif.NewExtractorChain(a.config, exifTool, a.dirs)
+	if err != nil {
+		return fmt.Errorf("建立中繼資料擷取器失敗: %v", err)
+	}
+
+	// 建立共用的地理編碼器，GeoJSON 只載入一次
+	if err := a.loadGeocoder(); err != nil {
+		return err
+	}
+	// 時區邊界資料很大，只在啟動時載入一次
+	if err := a.loadTimeZones(); err != nil {
+		return err
+	}
 
 	// 先計算總檔案數
 	totalFiles, ignoredFiles := 0, 0
-	err := filepath.Walk(a.config.SrcDir, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(a.config.SrcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -102,6 +137,10 @@ func (a *App) Run(ctx context.Context) error {
 				ignoredFiles++
 				return nil
 			}
+			// XMP、Google Takeout 的 sidecar 與伴隨檔案跟著媒體檔處理
+			if exif.IsXMPSidecar(path, a.dirs) || exif.IsTakeoutSidecar(path, a.config, a.dirs) || exif.IsCompanionFile(path, a.config, a.dirs) {
+				return nil
+			}
 			totalFiles++
 		}
 		return nil
@@ -114,72 +153,48 @@ func (a *App) Run(ctx context.Context) error {
 	a.progress.SetTotal(totalFiles)
 	a.stats.SetTotalFiles(totalFiles)
 
-	// 啟動工作池
+	// 第一階段：掃描來源資料夾並取得所有檔案的中繼資料
 	fmt.Printf("Workers 數量: %d，需處理總檔案數: %d，忽略的檔案數: %d\n", a.config.Workers, totalFiles, ignoredFiles)
 	a.logger.LogInfo("Start Workers",
 		zap.Int("workers", a.config.Workers),
 		zap.Int("total_files", totalFiles),
 	)
+	// 事件分群與旅行偵測需要所有檔案的拍攝時間，先取得所有檔案的中繼資料再複製；
+	// 其他情況取得中繼資料的批次直接交給複製檔案的 worker
+	var jobs <-chan *worker.Batch
+	if a.config.Events.Enabled || a.config.Home != nil {
+		fmt.Println("第一階段：取得中繼資料")
+		batches, err := a.collect(ctx, a.analyze(ctx, extractor, a.progress))
+		if err != nil {
+			return err
+		}
+		a.cluster(batches)
+
+		fmt.Println("第二階段：複製檔案")
+		a.progress.Reset()
+		queued := make(chan *worker.Batch, len(batches))
+		for _, batch := range batches {
+			queued <- batch
+		}
+		close(queued)
+		jobs = queued
+	} else {
+		// 進度只計算複製的檔案
+		jobs = a.analyze(ctx, extractor, progress.NewProgress())
+	}
+
+	// 分組並複製檔案
+	results := make(chan error, 100)
 
 	var wg sync.WaitGroup
 	for i := 0; i < a.config.Workers; i++ {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			worker.Worker(ctx, id, jobs, results, a.config, a.logger, a.progress, a.stats)
+			worker.Worker(ctx, id, jobs, results, a.config, a.geocoder, a.timeZones, a.dirs, a.logger, a.progress, a.stats)
 		}(i)
 	}
 
-	// 發送工作
-	go func() {
-		defer close(jobs)
-		err := filepath.Walk(a.config.SrcDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			// 檢查是否為目標目錄或其子目錄
-			if strings.HasPrefix(path, a.config.DstDir) {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-
-			if !info.IsDir() {
-				// 檢查是否要忽略此檔案
-				if a.config.ShouldIgnore(path) {
-					a.stats.IncrementIgnoredExt(filepath.Ext(path))
-					return nil
-				}
-
-				// 檢查是否為支援的格式
-				if a.config.IsSupportedFormat(path) {
-					select {
-					case <-ctx.Done():
-						a.logger.LogInfo("", zap.String("收到取消信號，停止發送工作", ""))
-						return ctx.Err()
-					case jobs <- path:
-					}
-				} else {
-					// 處理不支援的檔案
-					a.stats.IncrementUnsupportedExt(filepath.Ext(path))
-					if err := file.HandleUnsupportedFile(path, a.config, a.logger); err != nil {
-						a.logger.LogError(path, fmt.Sprintf("處理不支援的檔案失敗: %v", err))
-						a.stats.IncrementFailure()
-					} else {
-						a.logger.LogDebug(path, zap.String("處理不支援的檔案成功", filepath.Ext(path)))
-						a.stats.IncrementSuccess()
-					}
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			fmt.Printf("掃描檔案時發生錯誤: %v\n", err)
-		}
-	}()
-
 	// 等待所有工作完成
 	go func() {
 		wg.Wait()
@@ -210,6 +225,13 @@ func (a *App) Run(ctx context.Context) error {
 		)
 	}
 
+	// 輸出伴隨檔案統計
+	if len(stats.CompanionExts) > 0 {
+		a.logger.LogInfo("伴隨檔案統計",
+			zap.Any("companion_formats", stats.CompanionExts),
+		)
+	}
+
 	// 輸出被忽略的檔案格式統計
 	if len(stats.IgnoredExts) > 0 {
 		a.logger.LogInfo("被忽略的檔案格式統計",
@@ -225,7 +247,9 @@ func (a *App) Run(ctx context.Context) error {
 	// 驗證目錄
 	matchResult := ""
 	if a.config.EnableVerify {
-		result, err := verify.CompareDirectories(a.config.SrcDir, a.config.DstDir)
+		// JSON sidecar 複製時跟著媒體檔改名，takeout_json: drop 時刻意不複製，都不列入比對
+		exclude := func(path string) bool { return exif.IsTakeoutSidecar(path, a.config, a.dirs) }
+		result, err := verify.CompareDirectories(a.config.SrcDir, a.config.DstDir, exclude)
 		if err != nil {
 			a.logger.LogError("", fmt.Sprintf("驗證目錄失敗: %v", err))
 		}
@@ -243,6 +267,7 @@ func (a *App) Run(ctx context.Context) error {
 		zap.Int("total_files", stats.TotalFiles),
 		zap.Int("success_count", stats.SuccessCount),
 		zap.Int("failure_count", stats.FailureCount),
+		zap.Int("companion_count", stats.CompanionCount),
 		zap.String("result", matchResult),
 		zap.Duration("duration", duration),
 	)
@@ -250,6 +275,7 @@ func (a *App) Run(ctx context.Context) error {
 	fmt.Printf("總檔案數: %d\n", stats.TotalFiles)
 	fmt.Printf("成功處理: %d\n", stats.SuccessCount)
 	fmt.Printf("處理失敗: %d\n", stats.FailureCount)
+	fmt.Printf("伴隨檔案: %d\n", stats.CompanionCount)
 	fmt.Printf("目錄匹配結果: %s\n", matchResult)
 	fmt.Printf("處理時間: %v\n", duration)
 	fmt.Printf("========== 處理完成 ==========\n")
@@ -266,6 +292,213 @@ func (a *App) Run(ctx context.Context) error {
 	return nil
 }
 
+// analyze 掃描來源資料夾，不支援的檔案直接處理，其他檔案同一資料夾依 exif_batch_size 分批取得中繼資料，
+// 整個資料夾取得後一起分組，再依群組切成多批依完成的順序送到回傳的 channel，全部完成後關閉；analyzed 記錄已取得中繼資料的檔案數
+func (a *App) analyze(ctx context.Context, extractor exif.Extractor, analyzed *progress.Progress) <-chan *worker.Batch {
+	jobs := make(chan *worker.Batch, 100)
+	batches := make(chan *worker.Batch, 100)
+	grouped := make(chan *worker.Batch, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < a.config.Workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			worker.Analyzer(ctx, id, jobs, batches, extractor, a.logger, analyzed)
+		}(i)
+	}
+
+	// 發送工作，同一資料夾的檔案分批交給 worker
+	go func() {
+		defer close(jobs)
+		if err := a.dispatch(ctx, jobs); err != nil {
+			fmt.Printf("掃描檔案時發生錯誤: %v\n", err)
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(batches)
+	}()
+
+	// 同一資料夾的批次都取得中繼資料後才能配對
+	go func() {
+		defer close(grouped)
+		worker.Grouper(ctx, batches, grouped, a.config, a.timeZones, a.logger)
+	}()
+	return grouped
+}
+
+// collect 等待所有檔案取得中繼資料，回傳所有批次
+func (a *App) collect(ctx context.Context, batches <-chan *worker.Batch) ([]*worker.Batch, error) {
+	var result []*worker.Batch
+	for batch := range batches {
+		result = append(result, batch)
+	}
+	if err := ctx.Err(); err != nil {
+		a.logger.LogInfo("程式被取消",
+			zap.String("status", "canceled"),
+		)
+		return nil, fmt.Errorf("程式被取消: %v", err)
+	}
+	return result, nil
+}
+
+// dispatch 掃描來源資料夾，每個資料夾中需要取得中繼資料的檔案依 exif_batch_size 分成多批送到 jobs。
+// 配對的檔案（RAW+JPEG、Live Photo 的照片與影片）一定在同一個資料夾，每批記錄資料夾與批次數，由 Grouper 合併整個資料夾後再分組；
+// filepath.Walk 會在子資料夾之間穿插檔案，因此進入資料夾時就讀取資料夾中所有的檔案
+func (a *App) dispatch(ctx context.Context, jobs chan<- *worker.Batch) error {
+	return filepath.Walk(a.config.SrcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		// 檢查是否為目標目錄或其子目錄
+		if strings.HasPrefix(path, a.config.DstDir) {
+			return filepath.SkipDir
+		}
+
+		paths, err := a.collectDir(path)
+		if err != nil || len(paths) == 0 {
+			return err
+		}
+		size := a.config.ExifBatchSize
+		if size <= 0 {
+			size = len(paths)
+		}
+		parts := (len(paths) + size - 1) / size
+		for start := 0; start < len(paths); start += size {
+			job := &worker.Batch{Dir: path, Parts: parts, Paths: paths[start:min(start+size, len(paths))]}
+			select {
+			case <-ctx.Done():
+				a.logger.LogInfo("", zap.String("收到取消信號，停止發送工作", ""))
+				return ctx.Err()
+			case jobs <- job:
+			}
+		}
+		return nil
+	})
+}
+
+// collectDir 讀取資料夾中的檔案（不含子資料夾），不支援的檔案直接處理，回傳需要取得中繼資料的檔案
+func (a *App) collectDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || strings.HasPrefix(path, a.config.DstDir) {
+			continue
+		}
+
+		// 檢查是否要忽略此檔案
+		if a.config.ShouldIgnore(path) {
+			a.stats.IncrementIgnoredExt(filepath.Ext(path))
+			continue
+		}
+		if exif.IsXMPSidecar(path, a.dirs) || exif.IsTakeoutSidecar(path, a.config, a.dirs) || exif.IsCompanionFile(path, a.config, a.dirs) {
+			continue
+		}
+
+		// 檢查是否為支援的格式
+		if a.config.IsSupportedFormat(path) {
+			batch = append(batch, path)
+			continue
+		}
+
+		// 處理不支援的檔案
+		a.stats.IncrementUnsupportedExt(filepath.Ext(path))
+		if err := file.HandleUnsupportedFile(path, a.config, a.logger); err != nil {
+			a.logger.LogError(path, fmt.Sprintf("處理不支援的檔案失敗: %v", err))
+			a.stats.IncrementFailure()
+		} else {
+			a.logger.LogDebug(path, zap.String("處理不支援的檔案成功", filepath.Ext(path)))
+			a.stats.IncrementSuccess()
+		}
+	}
+	return batch, nil
+}
+
+// loadGeocoder 建立所有 worker 共用的地理編碼器，啟用地理位置標籤時無法建立即回傳錯誤，
+// 只有旅行偵測需要時改為記錄警告，旅行名稱不含國家
+func (a *App) loadGeocoder() error {
+	if !a.config.EnableGeoTag && a.config.Home == nil {
+		return nil
+	}
+
+	geocoder, err := geocoding.NewGeocoder(a.config.GeocoderType, map[string]interface{}{
+		"json_path": a.config.GeoJSONPath,
+		"fences":    a.config.Fences(),
+	})
+	if err != nil {
+		if a.config.EnableGeoTag {
+			return fmt.Errorf("建立地理編碼器失敗: %v", err)
+		}
+		a.logger.LogWarn("無法建立地理編碼器，旅行名稱不含國家", zap.Error(err))
+		return nil
+	}
+	a.geocoder = geocoder
+	return nil
+}
+
+// loadTimeZones 設定 timezone_json_path 時載入時區邊界資料，載入失敗時回傳錯誤
+func (a *App) loadTimeZones() error {
+	if a.config.TimeZoneJSONPath == "" {
+		return nil
+	}
+	timeZones, err := geocoding.NewGeoTimeZone(a.config.TimeZoneJSONPath)
+	if err != nil {
+		return fmt.Errorf("建立時區查詢器失敗: %v", err)
+	}
+	a.timeZones = timeZones
+	return nil
+}
+
+// cluster 依所有檔案的拍攝時間與位置分成事件與旅行，並將名稱設定到每個檔案的中繼資料
+func (a *App) cluster(batches []*worker.Batch) {
+	datas := make(map[string]*exif.ExifData)
+	for _, batch := range batches {
+		for path, data := range batch.ExifDatas {
+			datas[path] = data
+		}
+	}
+
+	// 事件名稱在啟用地理位置標籤時加上地點，旅行名稱一律加上國家
+	if a.config.Events.Enabled {
+		eventGeocoder := a.geocoder
+		if !a.config.EnableGeoTag {
+			eventGeocoder = nil
+		}
+		events := event.Cluster(datas, a.config, eventGeocoder, a.timeZones)
+		for _, e := range events {
+			a.logger.LogInfo("事件",
+				zap.String("name", e.Name()),
+				zap.Int("files", len(e.Paths)),
+			)
+		}
+		fmt.Printf("事件數: %d\n", len(events))
+	}
+
+	if a.config.Home != nil {
+		trips := event.DetectTrips(datas, a.config, a.geocoder, a.timeZones)
+		for _, t := range trips {
+			a.logger.LogInfo("旅行",
+				zap.String("name", t.Name()),
+				zap.Time("start", t.Start),
+				zap.Time("end", t.End),
+				zap.Int("files", len(t.Paths)),
+			)
+		}
+		fmt.Printf("旅行數: %d\n", len(trips))
+	}
+}
+
 // monitorProgress 監控處理進度
 func (a *App) monitorProgress(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
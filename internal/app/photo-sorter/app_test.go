@@ -0,0 +1,88 @@
+package photosorter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"photo-sorter/internal/app/photo-sorter/stats"
+	"photo-sorter/internal/app/photo-sorter/worker"
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+func TestDispatchKeepsPairsTogether(t *testing.T) {
+	srcDir := t.TempDir()
+	// 配對的檔案不相鄰：中間有其他檔案與子資料夾，RAW 與 JPEG 的檔名大小寫不同
+	files := []string{
+		"IMG_0001.HEIC", "IMG_0002.JPG", "IMG_0003.JPG", "IMG_0005/IMG_0005.JPG", "IMG_E0001.MOV",
+		"DSC_1234.NEF", "dsc_1234.jpg",
+	}
+	for _, name := range files {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := map[string]string{"IMG_0001.HEIC": "ID-1", "IMG_E0001.MOV": "ID-1"}
+
+	// exif_batch_size 比資料夾的檔案數小，同一資料夾分成多批取得中繼資料與複製
+	cfg := &config.Config{SrcDir: srcDir, DstDir: t.TempDir(), ExifBatchSize: 1,
+		Formats: []string{".heic", ".jpg", ".mov", ".nef"}}
+	a := &App{config: cfg, stats: stats.NewStats()}
+	jobs := make(chan *worker.Batch, len(files))
+	if err := a.dispatch(context.Background(), jobs); err != nil {
+		t.Fatalf("掃描失敗: %v", err)
+	}
+	close(jobs)
+
+	// 模擬 Analyzer 取得中繼資料，批次以相反的順序完成
+	var analyzed []*worker.Batch
+	for job := range jobs {
+		if len(job.Paths) > cfg.ExifBatchSize {
+			t.Errorf("批次有 %d 個檔案，超過 exif_batch_size", len(job.Paths))
+		}
+		job.ExifDatas = make(map[string]*exif.ExifData)
+		for _, path := range job.Paths {
+			job.ExifDatas[path] = &exif.ExifData{SourceFile: path, ContentIdentifier: ids[filepath.Base(path)]}
+		}
+		analyzed = append([]*worker.Batch{job}, analyzed...)
+	}
+	if len(analyzed) != len(files) {
+		t.Errorf("取得中繼資料的批次數 = %d, want %d", len(analyzed), len(files))
+	}
+	batches := make(chan *worker.Batch, len(analyzed))
+	for _, batch := range analyzed {
+		batches <- batch
+	}
+	close(batches)
+
+	grouped := make(chan *worker.Batch, len(files))
+	worker.Grouper(context.Background(), batches, grouped, cfg, nil, nil)
+	close(grouped)
+
+	paired := make(map[string][]string)
+	count := 0
+	for batch := range grouped {
+		count++
+		for _, g := range batch.Groups {
+			for _, m := range g.Members {
+				paired[filepath.Base(g.Primary)] = append(paired[filepath.Base(g.Primary)], filepath.Base(m.Path))
+			}
+		}
+	}
+
+	// 每組一批：兩組配對與三個單獨的檔案
+	if count != 5 {
+		t.Errorf("複製的批次數 = %d, want 5", count)
+	}
+	expected := map[string][]string{"IMG_0001.HEIC": {"IMG_E0001.MOV"}, "DSC_1234.NEF": {"dsc_1234.jpg"}}
+	if !reflect.DeepEqual(paired, expected) {
+		t.Errorf("配對結果 = %v, want %v", paired, expected)
+	}
+}
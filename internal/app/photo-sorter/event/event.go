@@ -0,0 +1,137 @@
+package event
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// eventDateLayout 事件名稱中的日期格式
+const eventDateLayout = "2006-01-02"
+
+// Event 一段連續拍攝的檔案
+type Event struct {
+	Start   time.Time
+	End     time.Time
+	Country string // 事件中最多檔案所在的國家，沒有地點時為空
+	City    string // 事件中最多檔案所在的城市，空白換成底線
+	Paths   []string
+}
+
+// Name 事件的資料夾名稱：開始日期_to_結束日期[-國家-城市]，開始與結束在同一天時只有日期
+func (e *Event) Name() string {
+	name := e.Start.Format(eventDateLayout)
+	if end := e.End.Format(eventDateLayout); end != name {
+		name += "_to_" + end
+	}
+	if e.Country != "" {
+		name += "-" + e.Country
+		if e.City != "" {
+			name += "-" + e.City
+		}
+	}
+	return name
+}
+
+// shot 有拍攝時間的檔案
+type shot struct {
+	path     string
+	data     *exif.ExifData
+	captured time.Time
+}
+
+// Cluster 依拍攝時間排序所有檔案，相鄰兩個檔案的間隔超過 max_gap，
+// 或有 GPS 的檔案與事件中上一個有 GPS 的檔案距離超過 max_distance_km 時切成新的事件，
+// 並將事件名稱設定到每個檔案的 Event。geocoder 為 nil 時事件名稱不含地點，沒有拍攝時間的檔案不屬於任何事件；
+// timeZones 用於查詢沒有時區的拍攝時間所在的時區，為 nil 時不查詢
+func Cluster(datas map[string]*exif.ExifData, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder) []*Event {
+	shots := sortedShots(datas, cfg, timeZones)
+
+	var events []*Event
+	var current []shot
+	var lastLat, lastLon float64
+	hasLast := false
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		e := &Event{Start: current[0].captured, End: current[len(current)-1].captured}
+		e.Country, e.City = location(current, geocoder)
+		for _, s := range current {
+			e.Paths = append(e.Paths, s.path)
+		}
+		name := e.Name()
+		for _, s := range current {
+			s.data.Event = name
+		}
+		events = append(events, e)
+		current, hasLast = nil, false
+	}
+
+	for _, s := range shots {
+		lat, lon, geotagged := s.data.Coordinates()
+		if len(current) > 0 {
+			split := s.captured.Sub(current[len(current)-1].captured) > cfg.Events.Gap()
+			if !split && geotagged && hasLast && cfg.Events.MaxDistanceKM > 0 {
+				split = geocoding.DistanceKM(lastLat, lastLon, lat, lon) > cfg.Events.MaxDistanceKM
+			}
+			if split {
+				flush()
+			}
+		}
+		current = append(current, s)
+		if geotagged {
+			lastLat, lastLon, hasLast = lat, lon, true
+		}
+	}
+	flush()
+	return events
+}
+
+// sortedShots 有拍攝時間的檔案，依拍攝時間排序，時間相同時依路徑排序
+func sortedShots(datas map[string]*exif.ExifData, cfg *config.Config, timeZones geocoding.TimeZoneGeocoder) []shot {
+	var shots []shot
+	for path, data := range datas {
+		if data == nil {
+			continue
+		}
+		if captured, ok := data.FolderTime(path, cfg, timeZones); ok {
+			shots = append(shots, shot{path: path, data: data, captured: captured})
+		}
+	}
+	sort.Slice(shots, func(i, j int) bool {
+		if !shots[i].captured.Equal(shots[j].captured) {
+			return shots[i].captured.Before(shots[j].captured)
+		}
+		return shots[i].path < shots[j].path
+	})
+	return shots
+}
+
+// location 事件中最多檔案所在的國家與城市，數量相同時使用先拍攝的地點
+func location(shots []shot, geocoder geocoding.Geocoder) (country, city string) {
+	if geocoder == nil {
+		return "", ""
+	}
+
+	counts := make(map[string]int)
+	best := 0
+	for _, s := range shots {
+		// 查詢結果保存在檔案的中繼資料，第二階段取得路徑與標籤時不再查詢
+		place, err := s.data.Locate(geocoder)
+		if err != nil || place == nil || place.Country == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s\x00%s", place.Country, place.FormatCity())
+		counts[key]++
+		if counts[key] > best {
+			best = counts[key]
+			country, city = place.Country, place.FormatCity()
+		}
+	}
+	return country, city
+}
@@ -0,0 +1,98 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// fakeGeocoder 依緯度回傳固定地點的地理編碼器
+type fakeGeocoder struct{}
+
+func (fakeGeocoder) GetLocationFromGPS(lat, lon float64) (*geocoding.CountryCity, error) {
+	if lat > 30 {
+		return &geocoding.CountryCity{Country: "JPN", City: "Tokyo"}, nil
+	}
+	return &geocoding.CountryCity{Country: "TWN", City: "New Taipei"}, nil
+}
+
+func TestCluster(t *testing.T) {
+	type shot struct {
+		path string
+		time string
+		lat  string
+		lon  string
+	}
+	tokyo := [2]string{`35 deg 41' 0.00" N`, `139 deg 41' 0.00" E`}
+	osaka := [2]string{`34 deg 41' 0.00" N`, `135 deg 30' 0.00" E`}
+	taipei := [2]string{`25 deg 2' 0.00" N`, `121 deg 33' 0.00" E`}
+
+	tests := []struct {
+		name        string
+		events      config.EventConfig
+		geocoder    geocoding.Geocoder
+		shots       []shot
+		expected    map[string]string
+		eventsCount int
+	}{
+		{
+			name:   "間隔超過 max_gap 時切分，跨日的事件顯示日期範圍",
+			events: config.EventConfig{MaxGap: "12h"},
+			shots: []shot{
+				{path: "a.jpg", time: "2024:05:03 10:00:00"},
+				{path: "b.jpg", time: "2024:05:03 20:00:00"},
+				{path: "c.jpg", time: "2024:05:04 07:00:00"},
+				{path: "d.jpg", time: "2024:05:06 09:00:00"},
+				{path: "e.jpg"},
+			},
+			expected: map[string]string{
+				"a.jpg": "2024-05-03_to_2024-05-04", "b.jpg": "2024-05-03_to_2024-05-04", "c.jpg": "2024-05-03_to_2024-05-04",
+				"d.jpg": "2024-05-06", "e.jpg": "",
+			},
+			eventsCount: 2,
+		},
+		{
+			name:     "GPS 距離超過 max_distance_km 時切分，名稱使用最多檔案的地點",
+			events:   config.EventConfig{MaxGap: "24h", MaxDistanceKM: 100},
+			geocoder: fakeGeocoder{},
+			shots: []shot{
+				{path: "a.jpg", time: "2024:05:03 10:00:00", lat: taipei[0], lon: taipei[1]},
+				{path: "b.jpg", time: "2024:05:03 18:00:00", lat: tokyo[0], lon: tokyo[1]},
+				{path: "c.jpg", time: "2024:05:04 09:00:00"},
+				{path: "d.jpg", time: "2024:05:05 09:00:00", lat: tokyo[0], lon: tokyo[1]},
+				{path: "e.jpg", time: "2024:05:05 19:00:00", lat: osaka[0], lon: osaka[1]},
+			},
+			expected: map[string]string{
+				"a.jpg": "2024-05-03-TWN-New_Taipei",
+				"b.jpg": "2024-05-03_to_2024-05-05-JPN-Tokyo", "c.jpg": "2024-05-03_to_2024-05-05-JPN-Tokyo", "d.jpg": "2024-05-03_to_2024-05-05-JPN-Tokyo",
+				"e.jpg": "2024-05-05-JPN-Tokyo",
+			},
+			eventsCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			datas := make(map[string]*exif.ExifData)
+			for _, s := range tt.shots {
+				datas[s.path] = &exif.ExifData{SourceFile: s.path, DateTimeOriginal: s.time, GPSLatitude: s.lat, GPSLongitude: s.lon}
+			}
+			cfg := &config.Config{DateFormat: "2006-01", Events: tt.events}
+
+			events := Cluster(datas, cfg, tt.geocoder, nil)
+			if len(events) != tt.eventsCount {
+				t.Errorf("事件數 = %d, want %d", len(events), tt.eventsCount)
+			}
+			got := make(map[string]string)
+			for path, data := range datas {
+				got[path] = data.Event
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Event = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package event
+
+import (
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// Trip 離家超過 home.radius_km 的一段連續拍攝
+type Trip struct {
+	Start   time.Time
+	End     time.Time
+	Country string // 旅行中最多離家檔案所在的國家，沒有地點時為空
+	Paths   []string
+}
+
+// Name 旅行的名稱：開始的年月-Trip[-國家]，例如 2024-05-Trip-JPN
+func (t *Trip) Name() string {
+	name := t.Start.Format("2006-01") + "-Trip"
+	if t.Country != "" {
+		name += "-" + t.Country
+	}
+	return name
+}
+
+// DetectTrips 依拍攝時間排序所有檔案，連續離家超過 home.radius_km 的有 GPS 檔案視為一趟旅行，
+// 中間沒有 GPS 的檔案依拍攝時間併入旅行；在家拍攝的檔案，或相鄰兩個離家檔案的間隔超過 home.max_gap 時結束旅行。
+// 旅行名稱設定到每個檔案的 Trip，geocoder 為 nil 時名稱不含國家；timeZones 用於查詢沒有時區的拍攝時間所在的時區
+func DetectTrips(datas map[string]*exif.ExifData, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder) []*Trip {
+	home := cfg.Home
+	if home == nil {
+		return nil
+	}
+
+	var trips []*Trip
+	var current, away, pending []shot
+	flush := func() {
+		if len(away) > 0 {
+			t := &Trip{Start: current[0].captured, End: current[len(current)-1].captured}
+			t.Country = country(away, geocoder)
+			name := t.Name()
+			for _, s := range current {
+				s.data.Trip = name
+				t.Paths = append(t.Paths, s.path)
+			}
+			trips = append(trips, t)
+		}
+		current, away, pending = nil, nil, nil
+	}
+
+	for _, s := range sortedShots(datas, cfg, timeZones) {
+		lat, lon, ok := s.data.Coordinates()
+		if !ok {
+			// 沒有 GPS 的檔案等到下一個離家的檔案出現時才併入旅行
+			if len(away) > 0 {
+				pending = append(pending, s)
+			}
+			continue
+		}
+		if geocoding.DistanceKM(home.Latitude, home.Longitude, lat, lon) <= home.RadiusKM {
+			flush()
+			continue
+		}
+		if len(away) > 0 && s.captured.Sub(away[len(away)-1].captured) > home.Gap() {
+			flush()
+		}
+		current = append(current, pending...)
+		current = append(current, s)
+		away = append(away, s)
+		pending = nil
+	}
+	flush()
+	return trips
+}
+
+// country 最多檔案所在的國家，數量相同時使用先拍攝的國家
+func country(shots []shot, geocoder geocoding.Geocoder) string {
+	if geocoder == nil {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	best, result := 0, ""
+	for _, s := range shots {
+		place, err := s.data.Locate(geocoder)
+		if err != nil || place == nil || place.Country == "" {
+			continue
+		}
+		counts[place.Country]++
+		if counts[place.Country] > best {
+			best, result = counts[place.Country], place.Country
+		}
+	}
+	return result
+}
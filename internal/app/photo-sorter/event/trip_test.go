@@ -0,0 +1,61 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+func TestDetectTrips(t *testing.T) {
+	type shot struct {
+		path string
+		time string
+		gps  [2]string
+	}
+	home := [2]string{`25 deg 2' 0.00" N`, `121 deg 33' 0.00" E`}
+	tokyo := [2]string{`35 deg 41' 0.00" N`, `139 deg 41' 0.00" E`}
+	none := [2]string{}
+
+	shots := []shot{
+		{"home1.jpg", "2024:05:01 10:00:00", home},
+		{"untagged0.jpg", "2024:05:02 10:00:00", none},
+		{"tokyo1.jpg", "2024:05:03 10:00:00", tokyo},
+		{"untagged1.jpg", "2024:05:04 10:00:00", none},
+		{"tokyo2.jpg", "2024:05:05 10:00:00", tokyo},
+		{"untagged2.jpg", "2024:05:06 10:00:00", none},
+		{"home2.jpg", "2024:05:07 10:00:00", home},
+		{"tokyo3.jpg", "2024:09:01 10:00:00", tokyo},
+		{"tokyo4.jpg", "2024:09:10 10:00:00", tokyo},
+	}
+	expected := map[string]string{
+		"home1.jpg": "", "untagged0.jpg": "",
+		"tokyo1.jpg": "2024-05-Trip-JPN", "untagged1.jpg": "2024-05-Trip-JPN", "tokyo2.jpg": "2024-05-Trip-JPN",
+		// 最後一個離家檔案之後、回家之前沒有 GPS 的檔案不併入旅行
+		"untagged2.jpg": "", "home2.jpg": "",
+		// 間隔超過 max_gap 時是不同的旅行
+		"tokyo3.jpg": "2024-09-Trip-JPN", "tokyo4.jpg": "2024-09-Trip-JPN",
+	}
+
+	datas := make(map[string]*exif.ExifData)
+	for _, s := range shots {
+		datas[s.path] = &exif.ExifData{SourceFile: s.path, DateTimeOriginal: s.time, GPSLatitude: s.gps[0], GPSLongitude: s.gps[1]}
+	}
+	cfg := &config.Config{
+		DateFormat: "2006-01",
+		Home:       &config.HomeConfig{Latitude: 25.0330, Longitude: 121.5654, RadiusKM: 50, MaxGap: "72h"},
+	}
+
+	trips := DetectTrips(datas, cfg, fakeGeocoder{}, nil)
+	if len(trips) != 3 {
+		t.Errorf("旅行數 = %d, want 3", len(trips))
+	}
+	got := make(map[string]string)
+	for path, data := range datas {
+		got[path] = data.Trip
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Trip = %v, want %v", got, expected)
+	}
+}
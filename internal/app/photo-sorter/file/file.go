@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"photo-sorter/internal/app/photo-sorter/group"
 	"photo-sorter/internal/pkg/config"
 	"photo-sorter/internal/pkg/exif"
 	"photo-sorter/internal/pkg/geocoding"
@@ -17,47 +18,208 @@ import (
 	"go.uber.org/zap"
 )
 
-// ProcessFile 處理單個檔案
-func ProcessFile(ctx context.Context, path string, cfg *config.Config, logger *logger.Logger) error {
-	// 檢查 context 是否已取消
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("處理被取消: %v", ctx.Err())
-	default:
+// Result 群組中單一檔案的處理結果
+type Result struct {
+	Path      string
+	Err       error
+	Companion bool // 跟著媒體檔的伴隨檔案（例如 .AAE），不計入總檔案數
+}
+
+// ProcessGroup 處理一組檔案：主要檔案依自己的中繼資料決定目標路徑，成員與伴隨檔案放在相同的資料夾並使用相同的檔名，
+// 回傳每個檔案的處理結果，第一個為主要檔案。geocoder、timeZones 與 dirs 由所有 worker 共用，geocoder 為 nil 時不加入地理位置
+func ProcessGroup(ctx context.Context, g *group.Group, exifDatas map[string]*exif.ExifData, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder, dirs *exif.DirCache, logger *logger.Logger) []Result {
+	return processGroup(ctx, g, exifDatas, make(map[string]bool), cfg, geocoder, timeZones, dirs, logger)
+}
+
+// processGroup 處理一組檔案，claimed 記錄已經跟著其他檔案處理的伴隨檔案
+// （例如 IMG_0001.AAE 同時符合 Live Photo 的照片與影片），避免重複複製
+func processGroup(ctx context.Context, g *group.Group, exifDatas map[string]*exif.ExifData, claimed map[string]bool, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder, dirs *exif.DirCache, logger *logger.Logger) []Result {
+	companionsOf := func(path string) []string {
+		var found []string
+		for _, companion := range exif.FindCompanions(path, cfg, dirs) {
+			if !claimed[companion] {
+				claimed[companion] = true
+				found = append(found, companion)
+			}
+		}
+		return found
+	}
+
+	// 主要檔案沒有中繼資料時與伴隨檔案一起移到失敗資料夾，成員各自處理
+	exifData := exifDatas[g.Primary]
+	if exifData == nil {
+		results := []Result{{Path: g.Primary, Err: failFile(ctx, g.Primary, logger, cfg)}}
+		for _, companion := range companionsOf(g.Primary) {
+			results = append(results, Result{Path: companion, Err: failFile(ctx, companion, logger, cfg), Companion: true})
+		}
+		for _, m := range g.Members {
+			results = append(results, processGroup(ctx, &group.Group{Primary: m.Path}, exifDatas, claimed, cfg, geocoder, timeZones, dirs, logger)...)
+		}
+		return results
+	}
+
+	// 依序排列要跟著主要檔案的檔案：主要檔案的伴隨檔案、每個成員與成員的伴隨檔案
+	var followers []exif.Companion
+	var results []Result
+	isCompanion := make(map[string]bool)
+	for _, companion := range companionsOf(g.Primary) {
+		followers = append(followers, exif.Companion{Path: companion})
+		isCompanion[companion] = true
+	}
+	for _, m := range g.Members {
+		subdir, skip := m.Placement(cfg)
+		if skip {
+			logger.LogInfo(m.Path, zap.String("略過", string(m.Role)), zap.String("主要檔案", g.Primary))
+			results = append(results, Result{Path: m.Path})
+			for _, companion := range companionsOf(m.Path) {
+				results = append(results, Result{Path: companion, Companion: true})
+			}
+			continue
+		}
+		followers = append(followers, exif.Companion{Path: m.Path, Subdir: subdir})
+		for _, companion := range companionsOf(m.Path) {
+			followers = append(followers, exif.Companion{Path: companion, Subdir: subdir})
+			isCompanion[companion] = true
+		}
 	}
 
-	// 取得 EXIF 資料
-	exifData, err := exif.GetExifData(path)
+	targetPath, err := placeFile(ctx, g.Primary, exifData, followers, cfg, geocoder, timeZones, dirs, logger)
 	if err != nil {
-		logger.LogInfo(path, zap.String("取得 EXIF 資料失敗", "將檔案移動到失敗資料夾"))
-		return HandelFailedFolder(path, cfg, logger)
+		results = append([]Result{{Path: g.Primary, Err: err}}, results...)
+		for _, f := range followers {
+			results = append(results, Result{Path: f.Path, Err: err, Companion: isCompanion[f.Path]})
+		}
+		return results
+	}
+
+	targets := []string{targetPath}
+	for _, f := range followers {
+		followerPath := exif.CompanionTarget(targetPath, f)
+		err := placeCompanion(g.Primary, f.Path, followerPath, cfg, dirs, logger)
+		results = append(results, Result{Path: f.Path, Err: err, Companion: isCompanion[f.Path]})
+		if err == nil && !isCompanion[f.Path] {
+			targets = append(targets, followerPath)
+		}
+	}
+
+	// 成員使用主要檔案的位置資訊加上標籤
+	primary := Result{Path: g.Primary}
+	for _, target := range targets {
+		if err := tagFile(ctx, target, exifData.Location, cfg); err != nil {
+			primary.Err = err
+			break
+		}
+	}
+	return append([]Result{primary}, results...)
+}
+
+// failFile 將取得 EXIF 資料失敗的檔案移到失敗資料夾
+func failFile(ctx context.Context, path string, logger *logger.Logger, cfg *config.Config) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("處理被取消: %v", err)
 	}
+	logger.LogInfo(path, zap.String("取得 EXIF 資料失敗", "將檔案移動到失敗資料夾"))
+	return HandelFailedFolder(path, cfg, logger)
+}
 
+// placeFile 查詢地點、取得目標路徑並複製檔案與 sidecar，companions 為跟著這個檔案的成員，用於避開檔名衝突
+func placeFile(ctx context.Context, path string, exifData *exif.ExifData, companions []exif.Companion, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder, dirs *exif.DirCache, logger *logger.Logger) (string, error) {
 	// 檢查 context 是否已取消
 	select {
 	case <-ctx.Done():
-		return fmt.Errorf("處理被取消: %v", ctx.Err())
+		return "", fmt.Errorf("處理被取消: %v", ctx.Err())
 	default:
 	}
 
+	// 查詢一次地點，目標路徑與標籤共用
+	if cfg.EnableGeoTag {
+		if _, err := exifData.Locate(geocoder); err != nil {
+			logger.LogError(path, fmt.Sprintf("取得目標路徑失敗: %v", err))
+			return "", fmt.Errorf("取得目標路徑失敗: %v", err)
+		}
+	}
+
 	// 取得目標路徑
-	targetPath, err := exif.GetTargetPath(path, exifData, cfg)
+	targetPath, err := exif.GetTargetPath(path, exifData, cfg, timeZones, companions...)
 	if err != nil {
 		logger.LogError(path, fmt.Sprintf("取得目標路徑失敗: %v", err))
-		return fmt.Errorf("取得目標路徑失敗: %v", err)
+		return "", fmt.Errorf("取得目標路徑失敗: %v", err)
+	}
+
+	if exifData.DatePattern != "" {
+		logger.LogInfo(path,
+			zap.String("從檔名推測日期", exifData.DateTimeOriginal),
+			zap.String("pattern", exifData.DatePattern),
+		)
+	}
+	if exifData.TimeShift != 0 {
+		logger.LogInfo(path, zap.String("修正拍攝時間", exifData.TimeShift.String()))
+	}
+	if exifData.ZoneUnknown {
+		logger.LogWarn(path, zap.String("拍攝地時區未知", "以 UTC 的日期分類，可設定 timezone_json_path 或 folder_time_zone"))
 	}
+	logger.LogDebug(path,
+		zap.String("target", targetPath),
+		zap.Any("sources", exifData.Sources),
+	)
 
 	if cfg.DryRun {
 		fmt.Printf("DryRun: 將移動: %s -> %s\n", path, targetPath)
-		return nil
+		return targetPath, nil
 	}
 
 	// 複製檔案
+	if err := CopyFile(path, targetPath); err != nil {
+		logger.LogError(path, fmt.Sprintf("複製檔案失敗: %v", err))
+		return "", fmt.Errorf("複製檔案失敗: %v", err)
+	}
+	copySidecars(path, targetPath, "", cfg, dirs, logger)
+	return targetPath, nil
+}
+
+// placeCompanion 將群組成員複製到主要檔案決定的位置
+func placeCompanion(primary, path, targetPath string, cfg *config.Config, dirs *exif.DirCache, logger *logger.Logger) error {
+	logger.LogDebug(path, zap.String("target", targetPath))
+
+	if cfg.DryRun {
+		fmt.Printf("DryRun: 將移動: %s -> %s\n", path, targetPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		logger.LogError(path, fmt.Sprintf("建立目標資料夾失敗: %v", err))
+		return fmt.Errorf("建立目標資料夾失敗: %v", err)
+	}
 	if err := CopyFile(path, targetPath); err != nil {
 		logger.LogError(path, fmt.Sprintf("複製檔案失敗: %v", err))
 		return fmt.Errorf("複製檔案失敗: %v", err)
 	}
+	copySidecars(path, targetPath, exif.FindXMPSidecar(primary, dirs), cfg, dirs, logger)
+	return nil
+}
+
+// copySidecars 複製媒體檔的 XMP 與 Google Takeout sidecar，失敗只記錄錯誤；
+// copied 為已經跟著主要檔案複製的 XMP sidecar（例如 IMG_0001.xmp 同時符合照片與影片），不重複複製
+func copySidecars(path, targetPath, copied string, cfg *config.Config, dirs *exif.DirCache, logger *logger.Logger) {
+	// XMP sidecar 一律跟著媒體檔，保留原本的命名方式
+	if sidecar := exif.FindXMPSidecar(path, dirs); sidecar != "" && sidecar != copied {
+		if err := CopyFile(sidecar, xmpTargetPath(sidecar, path, targetPath)); err != nil {
+			logger.LogError(sidecar, fmt.Sprintf("複製 sidecar 失敗: %v", err))
+		}
+	}
 
+	// 複製 Google Takeout sidecar，檔名跟著媒體檔的新檔名
+	if cfg.TakeoutJSON == "copy" {
+		if sidecar := exif.FindTakeoutSidecar(path, dirs); sidecar != "" {
+			if err := CopyFile(sidecar, targetPath+".json"); err != nil {
+				logger.LogError(sidecar, fmt.Sprintf("複製 sidecar 失敗: %v", err))
+			}
+		}
+	}
+}
+
+// tagFile 如果有啟用地理位置標籤且已查詢到地點，則為目標檔案添加標籤
+func tagFile(ctx context.Context, targetPath string, location *geocoding.CountryCity, cfg *config.Config) error {
 	// 檢查 context 是否已取消
 	select {
 	case <-ctx.Done():
@@ -65,45 +227,46 @@ func ProcessFile(ctx context.Context, path string, cfg *config.Config, logger *l
 	default:
 	}
 
-	// 如果有啟用地理位置標籤且有 GPS 資訊，則為目標檔案添加標籤
-	if cfg.EnableGeoTag && exifData.GPSLatitude != "" && exifData.GPSLongitude != "" {
-		lat, err := exif.ParseGPSString(exifData.GPSLatitude)
-		if err != nil {
-			return fmt.Errorf("解析緯度失敗: %v", err)
-		}
-
-		lon, err := exif.ParseGPSString(exifData.GPSLongitude)
-		if err != nil {
-			return fmt.Errorf("解析經度失敗: %v", err)
-		}
-
-		if lat != 0 && lon != 0 {
-			geocoder, err := geocoding.NewGeocoder(cfg.GeocoderType, map[string]interface{}{
-				"json_path": cfg.GeoJSONPath,
-			})
-			if err == nil {
-				countryCity, err := geocoder.GetLocationFromGPS(lat, lon)
-				if err == nil && countryCity != nil {
-					if !cfg.DryRun {
-						fileTagger, err := tagger.NewTagger()
-						if err != nil {
-							return fmt.Errorf("建立標籤實例失敗: %v", err)
-						}
-						tagName := fmt.Sprintf("%s-%s", countryCity.Country, strings.ReplaceAll(countryCity.City, " ", "_"))
-						if err := fileTagger.AddTag(targetPath, tagName); err != nil {
-							fmt.Printf("為檔案添加標籤失敗: %v\n", err)
-						}
-					} else {
-						fmt.Printf("DryRun: 為檔案添加標籤: %s\n", targetPath)
-					}
-				}
-			}
-		}
+	if !cfg.EnableGeoTag || location == nil {
+		return nil
+	}
+
+	var tagNames []string
+	if location.Country != "" {
+		tagNames = append(tagNames, fmt.Sprintf("%s-%s", location.Country, location.FormatCity()))
+	}
+	if location.Place != "" {
+		tagNames = append(tagNames, location.Place)
+	}
+	if len(tagNames) == 0 {
+		return nil
 	}
 
+	if cfg.DryRun {
+		fmt.Printf("DryRun: 為檔案添加標籤: %s\n", targetPath)
+		return nil
+	}
+	fileTagger, err := tagger.NewTagger()
+	if err != nil {
+		return fmt.Errorf("建立標籤實例失敗: %v", err)
+	}
+	for _, tagName := range tagNames {
+		if err := fileTagger.AddTag(targetPath, tagName); err != nil {
+			fmt.Printf("為檔案添加標籤失敗: %v\n", err)
+		}
+	}
 	return nil
 }
 
+// xmpTargetPath 依 sidecar 的命名方式決定目標檔名：IMG_1234.CR2.xmp 或 IMG_1234.xmp
+func xmpTargetPath(sidecar, path, targetPath string) string {
+	ext := filepath.Ext(sidecar)
+	if strings.EqualFold(strings.TrimSuffix(filepath.Base(sidecar), ext), filepath.Base(path)) {
+		return targetPath + ext
+	}
+	return strings.TrimSuffix(targetPath, filepath.Ext(targetPath)) + ext
+}
+
 // HandleUnsupportedFile 處理不支援的檔案
 func HandleUnsupportedFile(path string, cfg *config.Config, logger *logger.Logger) error {
 	// 建立 unknown_format 資料夾
@@ -60,3 +60,20 @@ func BenchmarkCopyFileDifferentSizes(b *testing.B) {
 		})
 	}
 }
+
+func TestXMPTargetPath(t *testing.T) {
+	tests := []struct {
+		sidecar  string
+		expected string
+	}{
+		{"/src/IMG_0001.CR2.xmp", "/dst/IMG_0001_1.CR2.xmp"},
+		{"/src/IMG_0001.xmp", "/dst/IMG_0001_1.xmp"},
+		{"/src/IMG_0001.XMP", "/dst/IMG_0001_1.XMP"},
+	}
+
+	for _, tt := range tests {
+		if got := xmpTargetPath(tt.sidecar, "/src/IMG_0001.CR2", "/dst/IMG_0001_1.CR2"); got != tt.expected {
+			t.Errorf("%s: 期望 %s，得到 %s", tt.sidecar, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package group
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// burstSequenceGap 依相機連拍序號判斷時，相鄰兩張的最大拍攝間隔
+const burstSequenceGap = time.Minute
+
+// frame 可能屬於連拍的一張照片
+type frame struct {
+	group  *Group
+	data   *exif.ExifData
+	time   time.Time
+	folder time.Time
+	device string
+}
+
+// DetectBursts 找出同一批中的連拍：iPhone 以 BurstUUID 判斷，其他相機依連續的 SequenceNumber，
+// 或同一台相機在 max_interval 內連續拍攝至少 min_frames 張。
+// 連拍中每張照片的 BurstDir 設為 burst_<第一張的拍攝時間>，best_frame 挑出的那張留在上層資料夾，回傳找到的連拍；
+// 資料夾名稱的時間與 GetTargetPath 相同，timeZones 用於查詢沒有時區的拍攝時間所在的時區
+func DetectBursts(groups []*Group, datas map[string]*exif.ExifData, cfg *config.Config, timeZones geocoding.TimeZoneGeocoder) [][]*Group {
+	if !cfg.Bursts.Enabled {
+		return nil
+	}
+
+	var frames []*frame
+	for _, g := range groups {
+		data := datas[g.Primary]
+		if data == nil || exif.MediaKind(g.Primary) == exif.KindVideo {
+			continue
+		}
+		capture, err := data.ResolveCaptureTime()
+		if err != nil {
+			continue
+		}
+		capture = data.CorrectCaptureTime(capture, cfg)
+		folder, _ := data.FolderTime(g.Primary, cfg, timeZones)
+		frames = append(frames, &frame{
+			group:  g,
+			data:   data,
+			time:   capture.Time,
+			folder: folder,
+			device: data.Make + "\x00" + data.Model + "\x00" + data.SerialNumber,
+		})
+	}
+
+	var bursts [][]*frame
+
+	// iPhone 同一次連拍共用 BurstUUID
+	var rest []*frame
+	byUUID := make(map[string][]*frame)
+	var uuids []string
+	for _, f := range frames {
+		if f.data.BurstUUID == "" {
+			rest = append(rest, f)
+			continue
+		}
+		if byUUID[f.data.BurstUUID] == nil {
+			uuids = append(uuids, f.data.BurstUUID)
+		}
+		byUUID[f.data.BurstUUID] = append(byUUID[f.data.BurstUUID], f)
+	}
+	for _, uuid := range uuids {
+		if burst := byUUID[uuid]; len(burst) >= 2 {
+			sortFrames(burst)
+			bursts = append(bursts, burst)
+		} else {
+			rest = append(rest, burst...)
+		}
+	}
+
+	// 其他照片依相機與拍攝時間排序，相鄰的照片序號連續或間隔夠短時視為同一次連拍
+	sortFrames(rest)
+	var run []*frame
+	bySequence := false
+	flush := func() {
+		if len(run) >= 2 && (bySequence || len(run) >= cfg.Bursts.MinFrames) {
+			bursts = append(bursts, run)
+		}
+		run, bySequence = nil, false
+	}
+	for _, f := range rest {
+		if len(run) > 0 {
+			prev := run[len(run)-1]
+			gap := f.time.Sub(prev.time)
+			sequence := prev.data.SequenceNumber > 0 && f.data.SequenceNumber == prev.data.SequenceNumber+1 && gap <= burstSequenceGap
+			// 序號從 1 重新開始表示是另一次連拍
+			restart := prev.data.SequenceNumber > 0 && f.data.SequenceNumber == 1
+			if f.device != prev.device || !(sequence || (gap <= cfg.Bursts.Interval() && !restart)) {
+				flush()
+			} else if sequence {
+				bySequence = true
+			}
+		}
+		run = append(run, f)
+	}
+	flush()
+
+	result := make([][]*Group, 0, len(bursts))
+	for _, burst := range bursts {
+		dir := "burst_" + burst[0].folder.Format("20060102_150405")
+		groups := make([]*Group, 0, len(burst))
+		for _, f := range burst {
+			f.data.BurstDir = dir
+			groups = append(groups, f.group)
+		}
+		if best := bestFrame(burst, cfg.Bursts.BestFrame); best != nil {
+			best.data.BurstDir = ""
+		}
+		result = append(result, groups)
+	}
+	return result
+}
+
+// bestFrame 依 best_frame 挑出連拍中代表的一張：largest 為最大的檔案，
+// keeper 為評分最高的一張（沒有評分時為最大的檔案），被標記為拒絕的照片不會被挑選
+func bestFrame(burst []*frame, mode string) *frame {
+	if mode != "largest" && mode != "keeper" {
+		return nil
+	}
+
+	var best *frame
+	var bestSize int64
+	for _, f := range burst {
+		if f.data.Rating < 0 {
+			continue
+		}
+		info, err := os.Stat(f.group.Primary)
+		if err != nil {
+			continue
+		}
+		switch {
+		case best == nil,
+			mode == "keeper" && f.data.Rating > best.data.Rating,
+			(mode == "largest" || f.data.Rating == best.data.Rating) && info.Size() > bestSize:
+			best, bestSize = f, info.Size()
+		}
+	}
+	return best
+}
+
+// sortFrames 依相機與拍攝時間排序
+func sortFrames(frames []*frame) {
+	sort.SliceStable(frames, func(i, j int) bool {
+		if frames[i].device != frames[j].device {
+			return frames[i].device < frames[j].device
+		}
+		return frames[i].time.Before(frames[j].time)
+	})
+}
@@ -0,0 +1,147 @@
+package group
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// fixedTimeZone 不論座標都回傳同一個時區
+type fixedTimeZone string
+
+func (z fixedTimeZone) GetTimeZoneFromGPS(lat, lon float64) (string, error) {
+	return string(z), nil
+}
+
+func TestDetectBursts(t *testing.T) {
+	type shot struct {
+		name   string
+		time   string
+		model  string
+		seq    int
+		uuid   string
+		rating int
+		size   int
+		utc    bool // 只有 UTC 時間並帶有 GPS，例如 Google Takeout 的照片
+	}
+	tests := []struct {
+		name      string
+		bestFrame string
+		timeZones geocoding.TimeZoneGeocoder
+		shots     []shot
+		expected  map[string]string // 檔名到 BurstDir
+	}{
+		{
+			name: "iPhone 依 BurstUUID",
+			shots: []shot{
+				{name: "IMG_0001.HEIC", time: "2024:05:03 10:20:30", model: "iPhone", uuid: "B1"},
+				{name: "IMG_0002.HEIC", time: "2024:05:03 10:20:35", model: "iPhone", uuid: "B1"},
+				{name: "IMG_0003.HEIC", time: "2024:05:03 10:20:35", model: "iPhone"},
+			},
+			expected: map[string]string{"IMG_0001.HEIC": "burst_20240503_102030", "IMG_0002.HEIC": "burst_20240503_102030", "IMG_0003.HEIC": ""},
+		},
+		{
+			name: "依連續的序號，序號從 1 重新開始為另一次連拍",
+			shots: []shot{
+				{name: "IMG_0001.JPG", time: "2024:05:03 10:20:30", model: "EOS R5", seq: 1},
+				{name: "IMG_0002.JPG", time: "2024:05:03 10:20:32", model: "EOS R5", seq: 2},
+				{name: "IMG_0003.JPG", time: "2024:05:03 10:20:32", model: "EOS R5", seq: 1},
+				{name: "IMG_0004.JPG", time: "2024:05:03 10:20:33", model: "EOS R5", seq: 2},
+			},
+			expected: map[string]string{
+				"IMG_0001.JPG": "burst_20240503_102030", "IMG_0002.JPG": "burst_20240503_102030",
+				"IMG_0003.JPG": "burst_20240503_102032", "IMG_0004.JPG": "burst_20240503_102032",
+			},
+		},
+		{
+			name: "依拍攝間隔，張數不足或不同相機不算連拍",
+			shots: []shot{
+				{name: "A.JPG", time: "2024:05:03 10:20:30", model: "X100V"},
+				{name: "B.JPG", time: "2024:05:03 10:20:31", model: "X100V"},
+				{name: "C.JPG", time: "2024:05:03 10:20:31", model: "X100V"},
+				{name: "D.JPG", time: "2024:05:03 10:20:31", model: "GR III"},
+				{name: "E.JPG", time: "2024:05:03 10:20:32", model: "GR III"},
+				{name: "F.JPG", time: "2024:05:03 10:20:35", model: "X100V"},
+			},
+			expected: map[string]string{
+				"A.JPG": "burst_20240503_102030", "B.JPG": "burst_20240503_102030", "C.JPG": "burst_20240503_102030",
+				"D.JPG": "", "E.JPG": "", "F.JPG": "",
+			},
+		},
+		{
+			name:      "largest 挑出最大的檔案",
+			bestFrame: "largest",
+			shots: []shot{
+				{name: "A.JPG", time: "2024:05:03 10:20:30", model: "X100V", size: 1},
+				{name: "B.JPG", time: "2024:05:03 10:20:30", model: "X100V", size: 3},
+				{name: "C.JPG", time: "2024:05:03 10:20:31", model: "X100V", size: 2, rating: 5},
+			},
+			expected: map[string]string{"A.JPG": "burst_20240503_102030", "B.JPG": "", "C.JPG": "burst_20240503_102030"},
+		},
+		{
+			name:      "keeper 挑出評分最高的一張",
+			bestFrame: "keeper",
+			shots: []shot{
+				{name: "A.JPG", time: "2024:05:03 10:20:30", model: "X100V", size: 1},
+				{name: "B.JPG", time: "2024:05:03 10:20:30", model: "X100V", size: 3, rating: -1},
+				{name: "C.JPG", time: "2024:05:03 10:20:31", model: "X100V", size: 2, rating: 5},
+			},
+			expected: map[string]string{"A.JPG": "burst_20240503_102030", "B.JPG": "burst_20240503_102030", "C.JPG": ""},
+		},
+		{
+			name:      "只有 UTC 時間時以 GPS 所在地的時區命名",
+			timeZones: fixedTimeZone("Asia/Tokyo"),
+			shots: []shot{
+				{name: "A.JPG", time: "2024:05:03 10:20:30", model: "X100V", utc: true},
+				{name: "B.JPG", time: "2024:05:03 10:20:31", model: "X100V", utc: true},
+				{name: "C.JPG", time: "2024:05:03 10:20:31", model: "X100V", utc: true},
+			},
+			expected: map[string]string{"A.JPG": "burst_20240503_192030", "B.JPG": "burst_20240503_192030", "C.JPG": "burst_20240503_192030"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			datas := make(map[string]*exif.ExifData)
+			for _, s := range tt.shots {
+				path := filepath.Join(dir, s.name)
+				if err := os.WriteFile(path, make([]byte, s.size), 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path)
+				data := &exif.ExifData{
+					SourceFile:     path,
+					Model:          s.model,
+					SequenceNumber: s.seq,
+					BurstUUID:      s.uuid,
+					Rating:         s.rating,
+				}
+				if s.utc {
+					data.MediaCreateDate = s.time
+					data.GPSLatitude, data.GPSLongitude = `35 deg 40' 52.32" N`, `139 deg 46' 1.56" E`
+				} else {
+					data.DateTimeOriginal = s.time
+				}
+				datas[path] = data
+			}
+			cfg := &config.Config{Bursts: config.BurstConfig{Enabled: true, MinFrames: 3, BestFrame: tt.bestFrame}}
+
+			DetectBursts(Build(paths, datas), datas, cfg, tt.timeZones)
+
+			got := make(map[string]string)
+			for path, data := range datas {
+				got[filepath.Base(path)] = data.BurstDir
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("BurstDir = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
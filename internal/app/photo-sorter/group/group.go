@@ -0,0 +1,180 @@
+package group
+
+import (
+	"path/filepath"
+	"strings"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+// Role 成員在群組中的角色
+type Role string
+
+const (
+	// RoleLiveMotion Live Photo 的影片
+	RoleLiveMotion Role = "live_motion"
+	// RoleRAWJPEG RAW+JPEG 拍攝時與 RAW 同時產生的 JPEG
+	RoleRAWJPEG Role = "raw_jpeg"
+)
+
+// jpegExts 可以與 RAW 配對的副檔名
+var jpegExts = map[string]bool{".jpg": true, ".jpeg": true}
+
+// liveImageExts 可以作為 Live Photo 照片的副檔名
+var liveImageExts = map[string]bool{".heic": true, ".heif": true, ".jpg": true, ".jpeg": true}
+
+// Member 跟著主要檔案處理的檔案
+type Member struct {
+	Path string
+	Role Role
+}
+
+// Placement 依設定決定成員放在主要檔案目標資料夾下的哪個子資料夾，skip 為 true 時不複製
+func (m Member) Placement(cfg *config.Config) (subdir string, skip bool) {
+	switch m.Role {
+	case RoleLiveMotion:
+		switch cfg.LivePhotos {
+		case "subfolder":
+			return "live", false
+		case "skip":
+			return "", true
+		}
+	case RoleRAWJPEG:
+		switch cfg.RAWJPEG {
+		case "jpeg_subfolder":
+			return "jpeg", false
+		case "raw_only":
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Group 一起處理的檔案，成員的目標資料夾與檔名跟著主要檔案
+type Group struct {
+	Primary string
+	Members []Member
+}
+
+// Paths 群組中的所有檔案，主要檔案在最前面
+func (g *Group) Paths() []string {
+	paths := []string{g.Primary}
+	for _, m := range g.Members {
+		paths = append(paths, m.Path)
+	}
+	return paths
+}
+
+// Build 將同一批的檔案分組，沒有配對的檔案自成一組，群組依主要檔案在 paths 中的順序排列。
+// RAW+JPEG 配對同一資料夾中檔名相同的 RAW 與 JPEG，RAW 為主要檔案；
+// Live Photo 先依 ContentIdentifier 配對照片與 MOV，沒有識別碼時配對同一資料夾中檔名相同的檔案
+func Build(paths []string, datas map[string]*exif.ExifData) []*Group {
+	groups := make(map[string]*Group, len(paths))
+	member := make(map[string]bool)
+
+	contentID := func(path string) string {
+		if data := datas[path]; data != nil {
+			return data.ContentIdentifier
+		}
+		return ""
+	}
+	attach := func(primary string, m Member) {
+		if groups[primary] == nil {
+			groups[primary] = &Group{Primary: primary}
+		}
+		groups[primary].Members = append(groups[primary].Members, m)
+		member[m.Path] = true
+	}
+
+	// RAW+JPEG：每個 RAW 配對一個 JPEG
+	raws := make(map[string]string)
+	for _, path := range paths {
+		if key := stemKey(path); exif.MediaKind(path) == exif.KindRAW && raws[key] == "" {
+			raws[key] = path
+		}
+	}
+	withJPEG := make(map[string]bool)
+	for _, path := range paths {
+		if raw := raws[stemKey(path)]; jpegExts[ext(path)] && raw != "" && !withJPEG[raw] {
+			withJPEG[raw] = true
+			attach(raw, Member{Path: path, Role: RoleRAWJPEG})
+		}
+	}
+
+	// Live Photo：照片與影片一對一配對，已經跟著 RAW 的 JPEG 不再配對
+	images := make(map[string]string)
+	byStem := make(map[string]string)
+	for _, path := range paths {
+		if liveImageExts[ext(path)] && !member[path] {
+			if id := contentID(path); id != "" && images[id] == "" {
+				images[id] = path
+			}
+			if key := stemKey(path); byStem[key] == "" {
+				byStem[key] = path
+			}
+		}
+	}
+	paired := make(map[string]bool)
+	for _, path := range paths {
+		if ext(path) != ".mov" {
+			continue
+		}
+		image := images[contentID(path)]
+		if contentID(path) == "" || image == "" {
+			// 沒有識別碼時配對相同檔名，但兩邊都有識別碼且不同時不是同一張 Live Photo
+			image = byStem[stemKey(path)]
+			if image != "" && contentID(image) != "" && contentID(path) != "" {
+				image = ""
+			}
+		}
+		if image != "" && !paired[image] {
+			paired[image] = true
+			attach(image, Member{Path: path, Role: RoleLiveMotion})
+		}
+	}
+
+	var result []*Group
+	for _, path := range paths {
+		if member[path] {
+			continue
+		}
+		if g := groups[path]; g != nil {
+			result = append(result, g)
+		} else {
+			result = append(result, &Group{Primary: path})
+		}
+	}
+	return result
+}
+
+// ext 小寫的副檔名
+func ext(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
+
+// stemKey 資料夾與不含副檔名的檔名，不分大小寫
+func stemKey(path string) string {
+	return strings.ToLower(strings.TrimSuffix(path, filepath.Ext(path)))
+}
+
+// Split 依群組邊界將 groups 分成多批，每批的檔案數不超過 size，檔案數超過 size 的群組自成一批；
+// size <= 0 時全部為一批
+func Split(groups []*Group, size int) [][]*Group {
+	var parts [][]*Group
+	var part []*Group
+	count := 0
+	for _, g := range groups {
+		n := len(g.Members) + 1
+		if len(part) > 0 && size > 0 && count+n > size {
+			parts = append(parts, part)
+			part, count = nil, 0
+		}
+		part = append(part, g)
+		count += n
+	}
+	if len(part) > 0 {
+		parts = append(parts, part)
+	}
+	return parts
+}
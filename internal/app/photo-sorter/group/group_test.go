@@ -0,0 +1,139 @@
+package group
+
+import (
+	"reflect"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []string
+		ids      map[string]string
+		expected map[string][]string
+	}{
+		{
+			name:     "依 ContentIdentifier 配對不同檔名",
+			paths:    []string{"/a/IMG_0001.HEIC", "/a/IMG_E0001.MOV"},
+			ids:      map[string]string{"/a/IMG_0001.HEIC": "ID-1", "/a/IMG_E0001.MOV": "ID-1"},
+			expected: map[string][]string{"/a/IMG_0001.HEIC": {"/a/IMG_E0001.MOV"}},
+		},
+		{
+			name:     "沒有識別碼時配對相同檔名",
+			paths:    []string{"/a/IMG_0002.jpg", "/a/img_0002.mov", "/a/IMG_0003.JPG"},
+			expected: map[string][]string{"/a/IMG_0002.jpg": {"/a/img_0002.mov"}, "/a/IMG_0003.JPG": nil},
+		},
+		{
+			name:     "識別碼不同時不配對",
+			paths:    []string{"/a/IMG_0004.HEIC", "/a/IMG_0004.MOV"},
+			ids:      map[string]string{"/a/IMG_0004.HEIC": "ID-1", "/a/IMG_0004.MOV": "ID-2"},
+			expected: map[string][]string{"/a/IMG_0004.HEIC": nil, "/a/IMG_0004.MOV": nil},
+		},
+		{
+			name:     "不同資料夾的相同檔名不配對",
+			paths:    []string{"/a/IMG_0005.HEIC", "/b/IMG_0005.MOV"},
+			expected: map[string][]string{"/a/IMG_0005.HEIC": nil, "/b/IMG_0005.MOV": nil},
+		},
+		{
+			name:     "RAW+JPEG 以 RAW 為主要檔案",
+			paths:    []string{"/a/DSC_1234.JPG", "/a/DSC_1234.NEF", "/a/DSC_1235.JPG"},
+			expected: map[string][]string{"/a/DSC_1234.NEF": {"/a/DSC_1234.JPG"}, "/a/DSC_1235.JPG": nil},
+		},
+		{
+			name:     "跟著 RAW 的 JPEG 不配對 Live Photo",
+			paths:    []string{"/a/IMG_0007.DNG", "/a/IMG_0007.JPG", "/a/IMG_0007.MOV"},
+			expected: map[string][]string{"/a/IMG_0007.DNG": {"/a/IMG_0007.JPG"}, "/a/IMG_0007.MOV": nil},
+		},
+		{
+			name:     "一張照片只配對一個影片",
+			paths:    []string{"/a/IMG_0006.HEIC", "/a/IMG_0006.MOV", "/a/IMG_0006.mov"},
+			expected: map[string][]string{"/a/IMG_0006.HEIC": {"/a/IMG_0006.MOV"}, "/a/IMG_0006.mov": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			datas := make(map[string]*exif.ExifData)
+			for _, path := range tt.paths {
+				datas[path] = &exif.ExifData{SourceFile: path, ContentIdentifier: tt.ids[path]}
+			}
+
+			got := make(map[string][]string)
+			for _, g := range Build(tt.paths, datas) {
+				var members []string
+				for _, m := range g.Members {
+					members = append(members, m.Path)
+				}
+				got[g.Primary] = members
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Build() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMemberPlacement(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   Role
+		cfg    config.Config
+		subdir string
+		skip   bool
+	}{
+		{"Live Photo together", RoleLiveMotion, config.Config{LivePhotos: "together"}, "", false},
+		{"Live Photo subfolder", RoleLiveMotion, config.Config{LivePhotos: "subfolder"}, "live", false},
+		{"Live Photo skip", RoleLiveMotion, config.Config{LivePhotos: "skip"}, "", true},
+		{"RAW+JPEG both", RoleRAWJPEG, config.Config{RAWJPEG: "both"}, "", false},
+		{"RAW+JPEG raw_only", RoleRAWJPEG, config.Config{RAWJPEG: "raw_only"}, "", true},
+		{"RAW+JPEG jpeg_subfolder", RoleRAWJPEG, config.Config{RAWJPEG: "jpeg_subfolder"}, "jpeg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Member{Path: "/a/IMG_0001", Role: tt.role}
+			subdir, skip := m.Placement(&tt.cfg)
+			if subdir != tt.subdir || skip != tt.skip {
+				t.Errorf("Placement() = %q, %v, want %q, %v", subdir, skip, tt.subdir, tt.skip)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	groups := []*Group{
+		{Primary: "A.NEF", Members: []Member{{Path: "A.JPG", Role: RoleRAWJPEG}}},
+		{Primary: "B.JPG"},
+		{Primary: "C.HEIC", Members: []Member{{Path: "C.MOV", Role: RoleLiveMotion}}},
+		{Primary: "D.JPG"},
+	}
+
+	tests := []struct {
+		name     string
+		size     int
+		expected [][]string
+	}{
+		{"不分批", 0, [][]string{{"A.NEF", "B.JPG", "C.HEIC", "D.JPG"}}},
+		{"依群組邊界分批", 3, [][]string{{"A.NEF", "B.JPG"}, {"C.HEIC", "D.JPG"}}},
+		{"群組超過上限時自成一批", 1, [][]string{{"A.NEF"}, {"B.JPG"}, {"C.HEIC"}, {"D.JPG"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, part := range Split(groups, tt.size) {
+				var primaries []string
+				for _, g := range part {
+					primaries = append(primaries, g.Primary)
+				}
+				got = append(got, primaries)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Split() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
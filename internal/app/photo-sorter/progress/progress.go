@@ -21,6 +21,20 @@ func (p *Progress) Update() {
 	p.processedFiles++
 }
 
+// Add 增加已處理檔案數
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processedFiles += n
+}
+
+// Reset 將已處理檔案數歸零，用於開始下一個階段
+func (p *Progress) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processedFiles = 0
+}
+
 // SetTotal 設定總檔案數
 func (p *Progress) SetTotal(total int) {
 	p.mu.Lock()
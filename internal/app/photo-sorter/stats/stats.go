@@ -9,16 +9,32 @@ type Stats struct {
 	FailureCount     int
 	UnsupportedCount int
 	IgnoredCount     int
+	CompanionCount   int
 	UnsupportedExts  map[string]int
 	IgnoredExts      map[string]int
+	CompanionExts    map[string]int
 	mu               sync.Mutex
 }
 
+// Snapshot 某個時間點的統計資訊，不含鎖，可以直接複製
+type Snapshot struct {
+	TotalFiles       int
+	SuccessCount     int
+	FailureCount     int
+	UnsupportedCount int
+	IgnoredCount     int
+	CompanionCount   int
+	UnsupportedExts  map[string]int
+	IgnoredExts      map[string]int
+	CompanionExts    map[string]int
+}
+
 // NewStats 建立新的統計實例
 func NewStats() *Stats {
 	return &Stats{
 		UnsupportedExts: make(map[string]int),
 		IgnoredExts:     make(map[string]int),
+		CompanionExts:   make(map[string]int),
 	}
 }
 
@@ -52,6 +68,14 @@ func (s *Stats) IncrementIgnoredExt(ext string) {
 	s.IgnoredCount++
 }
 
+// IncrementCompanionExt 增加跟著媒體檔搬移的伴隨檔案計數
+func (s *Stats) IncrementCompanionExt(ext string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CompanionExts[ext]++
+	s.CompanionCount++
+}
+
 // SetTotalFiles 設定總檔案數
 func (s *Stats) SetTotalFiles(total int) {
 	s.mu.Lock()
@@ -60,8 +84,27 @@ func (s *Stats) SetTotalFiles(total int) {
 }
 
 // GetStats 取得統計資訊
-func (s *Stats) GetStats() Stats {
+func (s *Stats) GetStats() Snapshot {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return *s
+	return Snapshot{
+		TotalFiles:       s.TotalFiles,
+		SuccessCount:     s.SuccessCount,
+		FailureCount:     s.FailureCount,
+		UnsupportedCount: s.UnsupportedCount,
+		IgnoredCount:     s.IgnoredCount,
+		CompanionCount:   s.CompanionCount,
+		UnsupportedExts:  copyCounts(s.UnsupportedExts),
+		IgnoredExts:      copyCounts(s.IgnoredExts),
+		CompanionExts:    copyCounts(s.CompanionExts),
+	}
+}
+
+// copyCounts 複製計數表，避免呼叫端讀取時與 worker 同時寫入
+func copyCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for k, v := range counts {
+		copied[k] = v
+	}
+	return copied
 }
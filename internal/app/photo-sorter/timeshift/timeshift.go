@@ -0,0 +1,113 @@
+package timeshift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+)
+
+// Change 單一檔案符合 time_corrections 的時間修正
+type Change struct {
+	Path      string
+	Original  time.Time     // 相機記錄的拍攝時間
+	Corrected time.Time     // 修正後的拍攝時間
+	Shift     time.Duration // 套用的位移
+}
+
+// Find 找出來源資料夾中符合 time_corrections 規則的檔案，dirs 為擷取器共用的資料夾檔名快取
+func Find(ctx context.Context, cfg *config.Config, extractor exif.Extractor, dirs *exif.DirCache) ([]Change, error) {
+	var changes []Change
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		datas, err := extractor.Extract(batch)
+		if errors.Is(err, exif.ErrExifToolClosed) {
+			return err
+		}
+		for _, path := range batch {
+			data := datas[path]
+			if data == nil {
+				continue
+			}
+			capture, err := data.ResolveCaptureTime()
+			if err != nil {
+				continue
+			}
+			corrected := data.CorrectCaptureTime(capture, cfg)
+			if data.TimeShift == 0 {
+				continue
+			}
+			changes = append(changes, Change{
+				Path:      path,
+				Original:  capture.Local(),
+				Corrected: corrected.Local(),
+				Shift:     data.TimeShift,
+			})
+		}
+		batch = nil
+		return nil
+	}
+
+	batchDir := ""
+	err := filepath.Walk(cfg.SrcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// 不處理目標資料夾中已整理的檔案
+		if cfg.DstDir != "" && strings.HasPrefix(path, cfg.DstDir) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || cfg.ShouldIgnore(path) || !cfg.IsSupportedFormat(path) {
+			return nil
+		}
+		if exif.IsXMPSidecar(path, dirs) || exif.IsTakeoutSidecar(path, cfg, dirs) {
+			return nil
+		}
+
+		if dir := filepath.Dir(path); dir != batchDir || len(batch) >= cfg.ExifBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			batchDir = dir
+		}
+		batch = append(batch, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("搜尋檔案失敗: %v", err)
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("讀取中繼資料失敗: %v", err)
+	}
+	return changes, nil
+}
+
+// Apply 將修正後的時間寫回檔案的中繼資料
+func Apply(ctx context.Context, changes []Change) error {
+	var errs []error
+	for _, change := range changes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := exif.WriteTimeShift(change.Path, change.Shift); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", change.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
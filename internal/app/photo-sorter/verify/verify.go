@@ -14,8 +14,8 @@ type CompareResult struct {
 	OnlyInTarget []string
 }
 
-// CompareDirectories 比對兩個目錄中的檔案
-func CompareDirectories(sourceDir, targetDir string) (*CompareResult, error) {
+// CompareDirectories 比對兩個目錄中的檔案，exclude 回傳 true 的檔案不列入比對（例如跟著媒體檔改名或不複製的 sidecar），可為 nil
+func CompareDirectories(sourceDir, targetDir string, exclude func(path string) bool) (*CompareResult, error) {
 	// 檢查目錄是否存在
 	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("來源目錄 '%s' 不存在", sourceDir)
@@ -25,12 +25,12 @@ func CompareDirectories(sourceDir, targetDir string) (*CompareResult, error) {
 	}
 
 	// 取得兩個目錄的檔案列表
-	sourceFiles, err := getFileList(sourceDir)
+	sourceFiles, err := getFileList(sourceDir, exclude)
 	if err != nil {
 		return nil, fmt.Errorf("讀取來源目錄失敗: %v", err)
 	}
 
-	targetFiles, err := getFileList(targetDir)
+	targetFiles, err := getFileList(targetDir, exclude)
 	if err != nil {
 		return nil, fmt.Errorf("讀取目標目錄失敗: %v", err)
 	}
@@ -120,14 +120,14 @@ func shouldIgnore(file string, patterns []string) bool {
 	return false
 }
 
-// getFileList 取得目錄中的所有檔案
-func getFileList(dir string) ([]string, error) {
+// getFileList 取得目錄中的所有檔案，略過 exclude 回傳 true 的檔案
+func getFileList(dir string, exclude func(path string) bool) ([]string, error) {
 	var files []string
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
+		if !info.IsDir() && (exclude == nil || !exclude(path)) {
 			// 取得相對於起始目錄的路徑
 			relPath, err := filepath.Rel(dir, path)
 			if err != nil {
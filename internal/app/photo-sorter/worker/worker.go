@@ -3,19 +3,127 @@ package worker
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"sort"
 
 	"photo-sorter/internal/app/photo-sorter/file"
+	"photo-sorter/internal/app/photo-sorter/group"
 	"photo-sorter/internal/app/photo-sorter/progress"
 	"photo-sorter/internal/app/photo-sorter/stats"
 	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/exif"
+	"photo-sorter/internal/pkg/geocoding"
 	"photo-sorter/internal/pkg/logger"
 
 	"go.uber.org/zap"
 )
 
-// Worker 處理檔案的工作者
-func Worker(ctx context.Context, id int, jobs <-chan string, results chan<- error, cfg *config.Config, logger *logger.Logger, progress *progress.Progress, stats *stats.Stats) {
-	for path := range jobs {
+// Batch 同一資料夾下的一批檔案與取得的中繼資料。掃描時每個資料夾依 exif_batch_size 分成 Parts 批取得中繼資料，
+// Grouper 合併同一資料夾的所有批次後分組，再依群組邊界切成多批交給 Worker，Groups 為這一批要處理的群組
+type Batch struct {
+	Dir       string
+	Parts     int
+	Paths     []string
+	ExifDatas map[string]*exif.ExifData
+	Groups    []*group.Group
+}
+
+// Analyzer 第一階段的工作者，整批取得 EXIF 資料，缺少資料的檔案在第二階段個別移到失敗資料夾
+func Analyzer(ctx context.Context, id int, jobs <-chan *Batch, batches chan<- *Batch, extractor exif.Extractor, logger *logger.Logger, progress *progress.Progress) {
+	for batch := range jobs {
+		select {
+		case <-ctx.Done():
+			logger.LogDebug("Analyzer 收到取消信號",
+				zap.Int("worker_id", id),
+				zap.String("status", "stopped"),
+			)
+			return
+		default:
+		}
+
+		logger.LogDebug("Analyzer 正在處理批次",
+			zap.Int("worker_id", id),
+			zap.Int("batch_size", len(batch.Paths)),
+		)
+
+		datas, err := extractor.Extract(batch.Paths)
+		if err != nil {
+			logger.LogError("", fmt.Sprintf("Worker %d 批次取得 EXIF 資料失敗: %v", id, err))
+		}
+		batch.ExifDatas = make(map[string]*exif.ExifData, len(batch.Paths))
+		for path, data := range datas {
+			batch.ExifDatas[path] = data
+		}
+		progress.Add(len(batch.Paths))
+
+		select {
+		case <-ctx.Done():
+			return
+		case batches <- batch:
+		}
+	}
+}
+
+// Grouper 合併同一資料夾分批取得的中繼資料，整個資料夾一起分組（RAW+JPEG、Live Photo）並偵測連拍，
+// 再依群組邊界切成不超過 exif_batch_size 個檔案的批次送到 grouped，同一資料夾的檔案可以由多個 Worker 同時處理
+func Grouper(ctx context.Context, batches <-chan *Batch, grouped chan<- *Batch, cfg *config.Config, timeZones geocoding.TimeZoneGeocoder, logger *logger.Logger) {
+	pending := make(map[string]*Batch)
+	for b := range batches {
+		dir := pending[b.Dir]
+		if dir == nil {
+			dir = &Batch{Dir: b.Dir, ExifDatas: make(map[string]*exif.ExifData)}
+			pending[b.Dir] = dir
+		}
+		dir.Paths = append(dir.Paths, b.Paths...)
+		for path, data := range b.ExifDatas {
+			dir.ExifDatas[path] = data
+		}
+		if dir.Parts++; dir.Parts < b.Parts {
+			continue
+		}
+		delete(pending, b.Dir)
+
+		for _, batch := range dir.split(cfg, timeZones, logger) {
+			select {
+			case <-ctx.Done():
+				return
+			case grouped <- batch:
+			}
+		}
+	}
+}
+
+// split 將整個資料夾的檔案分組並偵測連拍，再依群組邊界切成多批
+func (b *Batch) split(cfg *config.Config, timeZones geocoding.TimeZoneGeocoder, logger *logger.Logger) []*Batch {
+	// 各批完成的順序不一定，依檔名排序後與讀取資料夾的順序相同
+	sort.Strings(b.Paths)
+	groups := group.Build(b.Paths, b.ExifDatas)
+	for _, burst := range group.DetectBursts(groups, b.ExifDatas, cfg, timeZones) {
+		logger.LogDebug("偵測到連拍",
+			zap.String("dir", b.ExifDatas[burst[0].Primary].BurstDir),
+			zap.Int("frames", len(burst)),
+		)
+	}
+
+	var batches []*Batch
+	for _, part := range group.Split(groups, cfg.ExifBatchSize) {
+		batch := &Batch{Dir: b.Dir, ExifDatas: make(map[string]*exif.ExifData), Groups: part}
+		for _, g := range part {
+			for _, path := range g.Paths() {
+				batch.Paths = append(batch.Paths, path)
+				if data := b.ExifDatas[path]; data != nil {
+					batch.ExifDatas[path] = data
+				}
+			}
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// Worker 第二階段處理檔案的工作者，每個工作為同一資料夾下已分組的一批檔案，geocoder、timeZones 與 dirs 由所有 worker 共用
+func Worker(ctx context.Context, id int, jobs <-chan *Batch, results chan<- error, cfg *config.Config, geocoder geocoding.Geocoder, timeZones geocoding.TimeZoneGeocoder, dirs *exif.DirCache, logger *logger.Logger, progress *progress.Progress, stats *stats.Stats) {
+	for b := range jobs {
 		select {
 		case <-ctx.Done():
 			logger.LogDebug("Worker 收到取消信號",
@@ -24,23 +132,45 @@ func Worker(ctx context.Context, id int, jobs <-chan string, results chan<- erro
 			)
 			return
 		default:
+		}
+
+		logger.LogDebug("Worker 正在處理批次",
+			zap.Int("worker_id", id),
+			zap.Int("batch_size", len(b.Paths)),
+		)
+
+		// 同一組的 Live Photo 等檔案一起處理，成員跟著主要檔案放置
+		for _, g := range b.Groups {
 			logger.LogDebug("Worker 正在處理檔案",
 				zap.Int("worker_id", id),
-				zap.String("path", path),
+				zap.Strings("paths", g.Paths()),
 			)
-			progress.Update()
-			err := file.ProcessFile(ctx, path, cfg, logger)
-			if err != nil {
-				logger.LogError(path, fmt.Sprintf("Worker %d 處理失敗: %v", id, err))
-				stats.IncrementFailure()
-			} else {
-				logger.LogDebug("Worker 處理成功",
-					zap.Int("worker_id", id),
-					zap.String("path", path),
-				)
-				stats.IncrementSuccess()
+			for _, result := range file.ProcessGroup(ctx, g, b.ExifDatas, cfg, geocoder, timeZones, dirs, logger) {
+				path, err := result.Path, result.Err
+				// 伴隨檔案不計入總檔案數，只另外統計
+				if result.Companion {
+					if err != nil {
+						logger.LogError(path, fmt.Sprintf("Worker %d 處理伴隨檔案失敗: %v", id, err))
+						stats.IncrementFailure()
+					} else {
+						stats.IncrementCompanionExt(filepath.Ext(path))
+					}
+					continue
+				}
+
+				progress.Update()
+				if err != nil {
+					logger.LogError(path, fmt.Sprintf("Worker %d 處理失敗: %v", id, err))
+					stats.IncrementFailure()
+				} else {
+					logger.LogDebug("Worker 處理成功",
+						zap.Int("worker_id", id),
+						zap.String("path", path),
+					)
+					stats.IncrementSuccess()
+				}
+				results <- err
 			}
-			results <- err
 		}
 	}
 }
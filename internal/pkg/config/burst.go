@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// BurstConfig 連拍分組設定
+type BurstConfig struct {
+	Enabled     bool   `yaml:"enabled"`      // 是否將連拍放到目標資料夾下的 burst_<時間> 子資料夾
+	MaxInterval string `yaml:"max_interval"` // 相鄰兩張的最大拍攝間隔，預設 1s
+	MinFrames   int    `yaml:"min_frames"`   // 只依拍攝間隔判斷時的最少張數，預設 3
+	BestFrame   string `yaml:"best_frame"`   // 挑出一張放在上層資料夾：none 不挑選，largest 最大的檔案，keeper 評分最高的一張（沒有評分時為最大的檔案）
+
+	maxInterval time.Duration
+}
+
+// compile 套用預設值並檢查設定
+func (b *BurstConfig) compile() error {
+	if b.MaxInterval == "" {
+		b.MaxInterval = "1s"
+	}
+	interval, err := time.ParseDuration(b.MaxInterval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("無效的 max_interval %q", b.MaxInterval)
+	}
+	b.maxInterval = interval
+
+	if b.MinFrames <= 0 {
+		b.MinFrames = 3
+	}
+	if b.BestFrame == "" {
+		b.BestFrame = "none"
+	}
+	if b.BestFrame != "none" && b.BestFrame != "largest" && b.BestFrame != "keeper" {
+		return fmt.Errorf("無效的 best_frame %q", b.BestFrame)
+	}
+	return nil
+}
+
+// Interval 相鄰兩張視為同一次連拍的最大拍攝間隔
+func (b BurstConfig) Interval() time.Duration {
+	if b.maxInterval > 0 {
+		return b.maxInterval
+	}
+	if interval, err := time.ParseDuration(b.MaxInterval); err == nil && interval > 0 {
+		return interval
+	}
+	return time.Second
+}
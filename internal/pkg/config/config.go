@@ -4,13 +4,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"photo-sorter/internal/pkg/geocoding"
+	"photo-sorter/internal/pkg/layout"
 
 	"gopkg.in/yaml.v3"
 )
 
+// metadataExtractors metadata_extractors 可以使用的擷取器，與 exif.ExtractorType 對應
+var metadataExtractors = map[string]bool{
+	"xmp": true, "native": true, "exiftool": true, "sidecar": true, "filename": true, "mtime": true,
+}
+
 type Config struct {
 	SrcDir       string                 `yaml:"src_dir"`
 	DstDir       string                 `yaml:"dst_dir"`
@@ -24,6 +32,37 @@ type Config struct {
 	GeocoderType geocoding.GeocoderType `yaml:"geocoder_type"`  // 地理編碼器類型
 	LogLevel     string                 `yaml:"log_level"`      // 日誌等級：debug, info, warn, error
 	EnableVerify bool                   `yaml:"enable_verify"`  // 是否啟用驗證
+
+	ExifToolProcesses int `yaml:"exiftool_processes"` // exiftool 常駐程序數量，0 表示與 workers 相同
+	ExifBatchSize     int `yaml:"exif_batch_size"`    // 每次交給 exiftool 的檔案數上限
+
+	MetadataExtractors []string `yaml:"metadata_extractors"` // 中繼資料擷取器順序：xmp, native, exiftool, sidecar, filename, mtime
+	FilenamePatterns   []string `yaml:"filename_patterns"`   // 從檔名推測日期的正規表示式，先於內建規則比對
+	TakeoutJSON        string   `yaml:"takeout_json"`        // Google Takeout 的 JSON sidecar：copy 複製到媒體檔旁，drop 不複製
+
+	FolderTimeZone   string `yaml:"folder_time_zone"`   // 分類資料夾使用的時區（IANA 名稱），空白表示使用拍攝地的當地時間
+	TimeZoneJSONPath string `yaml:"timezone_json_path"` // 時區邊界 GeoJSON 檔案路徑，用於沒有時區資訊但有 GPS 的檔案
+
+	TimeCorrections []TimeCorrection `yaml:"time_corrections"` // 相機時鐘誤差的修正規則，依序比對，使用第一條符合的規則
+
+	PathTemplate   string       `yaml:"path_template"`   // 目標路徑樣板（Go text/template），空白表示使用預設的目錄結構
+	LayoutRules    []LayoutRule `yaml:"layout_rules"`    // 依媒體類型選擇目錄結構的規則，依序比對，沒有符合時使用 path_template
+	RenameTemplate string       `yaml:"rename_template"` // 檔名樣板（不含副檔名），空白表示保留原始檔名
+
+	LivePhotos string `yaml:"live_photos"` // Live Photo 的影片：together 與照片放在一起，subfolder 放到 live/ 子資料夾，skip 不複製
+	RAWJPEG    string `yaml:"raw_jpeg"`    // RAW+JPEG 的 JPEG：both 與 RAW 放在一起，raw_only 不複製，jpeg_subfolder 放到 jpeg/ 子資料夾
+
+	CompanionExtensions []string `yaml:"companion_extensions"` // 跟著同名媒體檔搬移的伴隨檔案副檔名，例如 .aae、.thm
+
+	Bursts BurstConfig `yaml:"bursts"` // 連拍分組
+	Events EventConfig `yaml:"events"` // 事件分群
+	Home   *HomeConfig `yaml:"home"`   // 住家位置，設定後偵測離家的旅行
+
+	Places []PlaceConfig `yaml:"places"` // 自訂地點，地理編碼時先於國家與城市比對
+
+	folderLocation *time.Location
+	pathTemplate   *layout.Template
+	renameTemplate *layout.NameTemplate
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -61,13 +100,154 @@ func LoadConfig(configPath string) (*Config, error) {
 	if cfg.GeocoderType == "" {
 		cfg.GeocoderType = geocoding.GeoStateType
 	}
+	if cfg.ExifBatchSize <= 0 {
+		cfg.ExifBatchSize = 50
+	}
+	if len(cfg.MetadataExtractors) == 0 {
+		cfg.MetadataExtractors = []string{"xmp", "native", "exiftool", "sidecar"}
+	}
+	if cfg.TakeoutJSON == "" {
+		cfg.TakeoutJSON = "copy"
+	}
+	if cfg.TakeoutJSON != "copy" && cfg.TakeoutJSON != "drop" {
+		return nil, fmt.Errorf("無效的 takeout_json 設定: %s", cfg.TakeoutJSON)
+	}
+	for _, extractor := range cfg.MetadataExtractors {
+		if !metadataExtractors[extractor] {
+			return nil, fmt.Errorf("無效的 metadata_extractors 設定: %s", extractor)
+		}
+	}
+	if cfg.CompanionExtensions == nil {
+		cfg.CompanionExtensions = []string{".aae", ".thm", ".lrv", ".srt"}
+	}
+	if cfg.LivePhotos == "" {
+		cfg.LivePhotos = "together"
+	}
+	if cfg.LivePhotos != "together" && cfg.LivePhotos != "subfolder" && cfg.LivePhotos != "skip" {
+		return nil, fmt.Errorf("無效的 live_photos 設定: %s", cfg.LivePhotos)
+	}
+	if cfg.RAWJPEG == "" {
+		cfg.RAWJPEG = "both"
+	}
+	if cfg.RAWJPEG != "both" && cfg.RAWJPEG != "raw_only" && cfg.RAWJPEG != "jpeg_subfolder" {
+		return nil, fmt.Errorf("無效的 raw_jpeg 設定: %s", cfg.RAWJPEG)
+	}
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = "info" // 預設日誌等級為 info
 	}
 
+	if cfg.FolderTimeZone != "" {
+		loc, err := time.LoadLocation(cfg.FolderTimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("無效的 folder_time_zone: %v", err)
+		}
+		cfg.folderLocation = loc
+	}
+
+	// 檢查路徑樣板
+	if cfg.PathTemplate != "" {
+		tmpl, err := layout.Parse(cfg.PathTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("無效的 path_template: %v", err)
+		}
+		cfg.pathTemplate = tmpl
+	}
+
+	// 檢查檔名樣板
+	if cfg.RenameTemplate != "" {
+		tmpl, err := layout.ParseName(cfg.RenameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("無效的 rename_template: %v", err)
+		}
+		cfg.renameTemplate = tmpl
+	}
+
+	// 檢查版面規則
+	for i := range cfg.LayoutRules {
+		if err := cfg.LayoutRules[i].compile(); err != nil {
+			return nil, fmt.Errorf("無效的 layout_rules 第 %d 條規則: %v", i+1, err)
+		}
+	}
+
+	// 檢查連拍分組設定
+	if err := cfg.Bursts.compile(); err != nil {
+		return nil, fmt.Errorf("無效的 bursts 設定: %v", err)
+	}
+
+	// 檢查事件分群設定
+	if err := cfg.Events.compile(); err != nil {
+		return nil, fmt.Errorf("無效的 events 設定: %v", err)
+	}
+
+	// 檢查住家位置
+	if cfg.Home != nil {
+		if err := cfg.Home.compile(); err != nil {
+			return nil, fmt.Errorf("無效的 home 設定: %v", err)
+		}
+	}
+
+	// 檢查自訂地點
+	for i := range cfg.Places {
+		if err := cfg.Places[i].compile(); err != nil {
+			return nil, fmt.Errorf("無效的 places 第 %d 個地點: %v", i+1, err)
+		}
+	}
+
+	// 檢查時間修正規則
+	for i := range cfg.TimeCorrections {
+		if err := cfg.TimeCorrections[i].compile(); err != nil {
+			return nil, fmt.Errorf("無效的 time_corrections 第 %d 條規則: %v", i+1, err)
+		}
+	}
+
+	// 檢查檔名規則
+	for _, pattern := range cfg.FilenamePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("無效的檔名規則 %q: %v", pattern, err)
+		}
+		if re.SubexpIndex("year") < 0 {
+			return nil, fmt.Errorf("檔名規則 %q 缺少 year 具名群組", pattern)
+		}
+	}
+
 	return &cfg, nil
 }
 
+// FolderLocation 分類資料夾使用的固定時區，nil 表示使用拍攝地的當地時間
+func (c *Config) FolderLocation() *time.Location {
+	if c.folderLocation != nil || c.FolderTimeZone == "" {
+		return c.folderLocation
+	}
+	loc, err := time.LoadLocation(c.FolderTimeZone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Layout 目標路徑樣板，沒有設定 path_template 時使用預設的目錄結構，啟用事件分群時以事件取代日期
+func (c *Config) Layout() (*layout.Template, error) {
+	if c.pathTemplate != nil {
+		return c.pathTemplate, nil
+	}
+	if c.PathTemplate != "" {
+		return layout.Parse(c.PathTemplate)
+	}
+	if c.Events.Enabled {
+		return layout.DefaultEvent, nil
+	}
+	return layout.Default, nil
+}
+
+// Rename 檔名樣板，沒有設定 rename_template 時為 nil
+func (c *Config) Rename() (*layout.NameTemplate, error) {
+	if c.renameTemplate != nil || c.RenameTemplate == "" {
+		return c.renameTemplate, nil
+	}
+	return layout.ParseName(c.RenameTemplate)
+}
+
 func (c *Config) ApplyFlags(srcDir, dstDir string, workers int) {
 	// 如果命令列有指定參數，則覆蓋設定檔的值
 	if srcDir != "." {
@@ -116,3 +296,14 @@ func (c *Config) IsSupportedFormat(path string) bool {
 	}
 	return false
 }
+
+// IsCompanionExt 副檔名是否在 companion_extensions 中
+func (c *Config) IsCompanionExt(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, companion := range c.CompanionExtensions {
+		if ext == strings.ToLower(companion) {
+			return true
+		}
+	}
+	return false
+}
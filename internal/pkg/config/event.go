@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventConfig 事件分群設定
+type EventConfig struct {
+	Enabled       bool    `yaml:"enabled"`         // 是否依拍攝時間的間隔將檔案分成事件
+	MaxGap        string  `yaml:"max_gap"`         // 相鄰兩個檔案的拍攝間隔超過此值時切成不同事件，預設 24h
+	MaxDistanceKM float64 `yaml:"max_distance_km"` // 相鄰兩個有 GPS 的檔案距離超過此值（公里）時切成不同事件，0 表示不依距離切分
+
+	maxGap time.Duration
+}
+
+// compile 套用預設值並檢查設定
+func (e *EventConfig) compile() error {
+	if e.MaxGap == "" {
+		e.MaxGap = "24h"
+	}
+	gap, err := time.ParseDuration(e.MaxGap)
+	if err != nil || gap <= 0 {
+		return fmt.Errorf("無效的 max_gap %q", e.MaxGap)
+	}
+	e.maxGap = gap
+
+	if e.MaxDistanceKM < 0 {
+		return fmt.Errorf("無效的 max_distance_km %v", e.MaxDistanceKM)
+	}
+	return nil
+}
+
+// Gap 相鄰兩個檔案視為同一個事件的最大拍攝間隔
+func (e EventConfig) Gap() time.Duration {
+	if e.maxGap > 0 {
+		return e.maxGap
+	}
+	if gap, err := time.ParseDuration(e.MaxGap); err == nil && gap > 0 {
+		return gap
+	}
+	return 24 * time.Hour
+}
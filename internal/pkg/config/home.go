@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// HomeConfig 住家位置，離家超過 radius_km 的連續拍攝視為旅行
+type HomeConfig struct {
+	Latitude  float64 `yaml:"latitude"`  // 緯度（十進位）
+	Longitude float64 `yaml:"longitude"` // 經度（十進位）
+	RadiusKM  float64 `yaml:"radius_km"` // 離家超過此距離（公里）視為外出旅行，預設 50
+	MaxGap    string  `yaml:"max_gap"`   // 同一趟旅行中相鄰兩個離家檔案的最大拍攝間隔，預設 72h
+
+	maxGap time.Duration
+}
+
+// compile 套用預設值並檢查設定
+func (h *HomeConfig) compile() error {
+	if h.Latitude < -90 || h.Latitude > 90 {
+		return fmt.Errorf("無效的 latitude %v", h.Latitude)
+	}
+	if h.Longitude < -180 || h.Longitude > 180 {
+		return fmt.Errorf("無效的 longitude %v", h.Longitude)
+	}
+	if h.RadiusKM == 0 {
+		h.RadiusKM = 50
+	}
+	if h.RadiusKM < 0 {
+		return fmt.Errorf("無效的 radius_km %v", h.RadiusKM)
+	}
+
+	if h.MaxGap == "" {
+		h.MaxGap = "72h"
+	}
+	gap, err := time.ParseDuration(h.MaxGap)
+	if err != nil || gap <= 0 {
+		return fmt.Errorf("無效的 max_gap %q", h.MaxGap)
+	}
+	h.maxGap = gap
+	return nil
+}
+
+// Gap 同一趟旅行中相鄰兩個離家檔案的最大拍攝間隔
+func (h HomeConfig) Gap() time.Duration {
+	if h.maxGap > 0 {
+		return h.maxGap
+	}
+	if gap, err := time.ParseDuration(h.MaxGap); err == nil && gap > 0 {
+		return gap
+	}
+	return 72 * time.Hour
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"photo-sorter/internal/pkg/layout"
+)
+
+// layoutRuleFields layout_rules 的 metadata 可以比對的欄位
+var layoutRuleFields = map[string]bool{
+	"make": true, "model": true, "lens": true, "serial_number": true, "name": true, "dir": true,
+}
+
+// MediaInfo 比對版面規則時需要的檔案資訊，MIME 類型與尺寸只在規則用到時才取得
+type MediaInfo interface {
+	Ext() string
+	Kind() string
+	MIMEType() string
+	Dimensions() (width, height int)
+	Metadata(field string) string
+}
+
+// LayoutRule 依媒體類型選擇目錄結構的規則，所有有設定的條件都符合時才使用這條規則
+type LayoutRule struct {
+	Name       string            `yaml:"name"`       // 規則名稱，只用於日誌與錯誤訊息
+	Extensions []string          `yaml:"extensions"` // 副檔名，例如 [".mp4", ".mov"]
+	Kinds      []string          `yaml:"kinds"`      // 媒體類型：photo、video、raw
+	MIMETypes  []string          `yaml:"mime_types"` // 依檔案內容判斷的 MIME 類型，可用 "video/*"
+	MinWidth   int               `yaml:"min_width"`  // 最小寬度（像素），0 表示不限制
+	MaxWidth   int               `yaml:"max_width"`  // 最大寬度（像素），0 表示不限制
+	MinHeight  int               `yaml:"min_height"` // 最小高度（像素），0 表示不限制
+	MaxHeight  int               `yaml:"max_height"` // 最大高度（像素），0 表示不限制
+	Metadata   map[string]string `yaml:"metadata"`   // 欄位到正規表示式，欄位：make、model、lens、serial_number、name、dir
+
+	PathTemplate string `yaml:"path_template"` // 目標路徑樣板，空白表示使用最上層的 path_template
+	DateFormat   string `yaml:"date_format"`   // 日期格式，空白表示使用最上層的 date_format
+
+	pathTemplate *layout.Template
+	metadata     map[string]*regexp.Regexp
+}
+
+// compile 解析路徑樣板與 metadata 的正規表示式
+func (r *LayoutRule) compile() error {
+	if r.PathTemplate != "" {
+		tmpl, err := layout.Parse(r.PathTemplate)
+		if err != nil {
+			return fmt.Errorf("無效的 path_template: %v", err)
+		}
+		r.pathTemplate = tmpl
+	}
+
+	r.metadata = make(map[string]*regexp.Regexp, len(r.Metadata))
+	for field, pattern := range r.Metadata {
+		if !layoutRuleFields[field] {
+			return fmt.Errorf("不支援的 metadata 欄位: %s", field)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("無效的 metadata 規則 %q: %v", pattern, err)
+		}
+		r.metadata[field] = re
+	}
+
+	for _, pattern := range r.MIMETypes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("無效的 mime_types %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches 判斷檔案是否符合規則，依成本由低到高檢查條件
+func (r *LayoutRule) matches(info MediaInfo) bool {
+	if len(r.Extensions) > 0 && !containsFold(r.Extensions, info.Ext()) {
+		return false
+	}
+	if len(r.Kinds) > 0 && !containsFold(r.Kinds, info.Kind()) {
+		return false
+	}
+	for field, re := range r.metadata {
+		if !re.MatchString(info.Metadata(field)) {
+			return false
+		}
+	}
+
+	if len(r.MIMETypes) > 0 {
+		mimeType := info.MIMEType()
+		matched := false
+		for _, pattern := range r.MIMETypes {
+			if ok, _ := path.Match(strings.ToLower(pattern), mimeType); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if r.MinWidth > 0 || r.MaxWidth > 0 || r.MinHeight > 0 || r.MaxHeight > 0 {
+		width, height := info.Dimensions()
+		if width <= 0 || height <= 0 {
+			return false
+		}
+		if (r.MinWidth > 0 && width < r.MinWidth) || (r.MaxWidth > 0 && width > r.MaxWidth) ||
+			(r.MinHeight > 0 && height < r.MinHeight) || (r.MaxHeight > 0 && height > r.MaxHeight) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold 不分大小寫判斷 values 是否包含 s
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectLayout 依序比對 layout_rules，回傳第一條符合的規則的路徑樣板與日期格式，
+// 沒有符合的規則時使用最上層的 path_template 與 date_format
+func (c *Config) SelectLayout(info MediaInfo) (tmpl *layout.Template, dateFormat string, err error) {
+	for i := range c.LayoutRules {
+		rule := &c.LayoutRules[i]
+		if rule.metadata == nil {
+			// 沒有經過 LoadConfig 的設定，先解析一份副本
+			compiled := *rule
+			if err := compiled.compile(); err != nil {
+				return nil, "", fmt.Errorf("layout_rules %s: %v", rule.Name, err)
+			}
+			rule = &compiled
+		}
+		if !rule.matches(info) {
+			continue
+		}
+
+		tmpl, dateFormat = rule.pathTemplate, rule.DateFormat
+		if tmpl == nil {
+			if tmpl, err = c.Layout(); err != nil {
+				return nil, "", err
+			}
+		}
+		if dateFormat == "" {
+			dateFormat = c.DateFormat
+		}
+		return tmpl, dateFormat, nil
+	}
+
+	tmpl, err = c.Layout()
+	return tmpl, c.DateFormat, err
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// PlaceConfig 使用者自訂的地點，以圓心與半徑或 GeoJSON 多邊形定義範圍
+type PlaceConfig struct {
+	Name      string  `yaml:"name"`      // 地點名稱，例如 Office
+	Latitude  float64 `yaml:"latitude"`  // 圓心緯度（十進位）
+	Longitude float64 `yaml:"longitude"` // 圓心經度（十進位）
+	RadiusKM  float64 `yaml:"radius_km"` // 半徑（公里），預設 0.2
+	GeoJSON   string  `yaml:"geojson"`   // 多邊形 GeoJSON 檔案路徑，設定時忽略圓心與半徑
+
+	fence *geocoding.GeoFence
+}
+
+// compile 套用預設值並建立地點範圍
+func (p *PlaceConfig) compile() error {
+	if p.Name == "" {
+		return errors.New("缺少 name")
+	}
+	if p.GeoJSON != "" {
+		fence, err := geocoding.NewPolygonFence(p.Name, p.GeoJSON)
+		if err != nil {
+			return err
+		}
+		p.fence = fence
+		return nil
+	}
+
+	if p.Latitude < -90 || p.Latitude > 90 {
+		return fmt.Errorf("無效的 latitude %v", p.Latitude)
+	}
+	if p.Longitude < -180 || p.Longitude > 180 {
+		return fmt.Errorf("無效的 longitude %v", p.Longitude)
+	}
+	if p.RadiusKM == 0 {
+		p.RadiusKM = 0.2
+	}
+	fence, err := geocoding.NewCircleFence(p.Name, p.Latitude, p.Longitude, p.RadiusKM)
+	if err != nil {
+		return err
+	}
+	p.fence = fence
+	return nil
+}
+
+// Fences 自訂地點的範圍，依 places 的順序排列，無法建立的地點略過
+func (c *Config) Fences() []*geocoding.GeoFence {
+	var fences []*geocoding.GeoFence
+	for i := range c.Places {
+		place := &c.Places[i]
+		if place.fence == nil && place.compile() != nil {
+			continue
+		}
+		fences = append(fences, place.fence)
+	}
+	return fences
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 時間修正規則的日期格式
+const (
+	correctionDateLayout     = "2006-01-02"
+	correctionDateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// TimeCorrection 相機時鐘誤差的修正規則，符合 Model（與選填的序號、日期範圍）的檔案拍攝時間加上 Shift
+type TimeCorrection struct {
+	Model        string `yaml:"model"`         // 相機型號，與 EXIF 的 Model 相同（不分大小寫）
+	SerialNumber string `yaml:"serial_number"` // 機身序號，選填，同型號有多台相機時區分
+	From         string `yaml:"from"`          // 起始時間（相機時鐘），選填，格式 2006-01-02 或 2006-01-02 15:04:05
+	To           string `yaml:"to"`            // 結束時間（相機時鐘），選填，只有日期時包含當天
+	Shift        string `yaml:"shift"`         // 要加上的時間，例如 1h7m 或 -1h7m
+
+	compiled bool
+	shift    time.Duration
+	from, to time.Time // 沒有設定的日期為零值
+}
+
+// compile 解析位移與日期範圍
+func (t *TimeCorrection) compile() error {
+	if strings.TrimSpace(t.Model) == "" {
+		return fmt.Errorf("缺少 model")
+	}
+	shift, err := time.ParseDuration(t.Shift)
+	if err != nil {
+		return fmt.Errorf("無效的 shift %q: %v", t.Shift, err)
+	}
+
+	var from, to time.Time
+	if t.From != "" {
+		if from, _, err = parseCorrectionTime(t.From); err != nil {
+			return fmt.Errorf("無效的 from %q: %v", t.From, err)
+		}
+	}
+	if t.To != "" {
+		var dateOnly bool
+		if to, dateOnly, err = parseCorrectionTime(t.To); err != nil {
+			return fmt.Errorf("無效的 to %q: %v", t.To, err)
+		}
+		// 只有日期時包含當天
+		if dateOnly {
+			to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return fmt.Errorf("to 早於 from")
+	}
+
+	t.shift, t.from, t.to, t.compiled = shift, from, to, true
+	return nil
+}
+
+// parseCorrectionTime 解析日期或日期時間，dateOnly 表示只有日期
+func parseCorrectionTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(correctionDateTimeLayout, value); err == nil {
+		return t, false, nil
+	}
+	t, err = time.Parse(correctionDateLayout, value)
+	return t, true, err
+}
+
+// TimeShift 取得符合相機型號、序號與相機時鐘時間的第一條修正規則的位移，
+// clock 為相機記錄的當地時間，只比較時鐘上的數值
+func (c *Config) TimeShift(model, serial string, clock time.Time) (time.Duration, bool) {
+	clock = time.Date(clock.Year(), clock.Month(), clock.Day(), clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), time.UTC)
+	for i := range c.TimeCorrections {
+		rule := &c.TimeCorrections[i]
+		if !strings.EqualFold(strings.TrimSpace(rule.Model), strings.TrimSpace(model)) {
+			continue
+		}
+		if rule.SerialNumber != "" && !strings.EqualFold(strings.TrimSpace(rule.SerialNumber), strings.TrimSpace(serial)) {
+			continue
+		}
+		if !rule.compiled {
+			// 沒有經過 LoadConfig 的設定，先解析一份副本
+			compiled := *rule
+			if err := compiled.compile(); err != nil {
+				continue
+			}
+			rule = &compiled
+		}
+		if (!rule.from.IsZero() && clock.Before(rule.from)) || (!rule.to.IsZero() && clock.After(rule.to)) {
+			continue
+		}
+		return rule.shift, true
+	}
+	return 0, false
+}
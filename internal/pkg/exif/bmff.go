@@ -0,0 +1,404 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxBoxes 單一層最多讀取的 box 數，避免損毀檔案造成大量配置
+	maxBoxes = 4096
+	// maxExifItemSize HEIF Exif 項目最多讀取的位元組數
+	maxExifItemSize = 4 * 1024 * 1024
+)
+
+// canonCR3UUID CR3 中存放 CMT1~CMT4 中繼資料的 uuid box
+var canonCR3UUID = []byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+// heifBrands HEIF/HEIC/AVIF 的 ftyp 品牌
+var heifBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "hevm": true, "hevs": true,
+	"mif1": true, "msf1": true, "avif": true,
+}
+
+var errBoxNotFound = errors.New("找不到指定的 box")
+
+// bmffBox ISO base media file format 的 box，offset/size 只涵蓋內容（不含標頭）
+type bmffBox struct {
+	typ    string
+	uuid   []byte
+	offset int64
+	size   int64
+}
+
+// readBoxes 列出 [start, end) 範圍內同一層的所有 box
+func readBoxes(r io.ReaderAt, start, end int64) ([]bmffBox, error) {
+	var boxes []bmffBox
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if len(boxes) >= maxBoxes {
+			return boxes, fmt.Errorf("box 數量過多")
+		}
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return boxes, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		box := bmffBox{typ: string(header[4:8])}
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// 延伸到範圍結尾
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return boxes, err
+			}
+			largeSize := binary.BigEndian.Uint64(header[8:16])
+			if largeSize > uint64(end-offset) {
+				return boxes, fmt.Errorf("box %q 大小超出範圍", box.typ)
+			}
+			size = int64(largeSize)
+			headerSize = 16
+		}
+		if box.typ == "uuid" {
+			box.uuid = make([]byte, 16)
+			if _, err := r.ReadAt(box.uuid, offset+headerSize); err != nil {
+				return boxes, err
+			}
+			headerSize += 16
+		}
+		if size < headerSize || offset+size > end {
+			return boxes, fmt.Errorf("box %q 大小無效: %d", box.typ, size)
+		}
+
+		box.offset = offset + headerSize
+		box.size = size - headerSize
+		boxes = append(boxes, box)
+		offset += size
+	}
+	return boxes, nil
+}
+
+// findBox 在 [start, end) 範圍內尋找指定類型的 box
+func findBox(r io.ReaderAt, start, end int64, typ string) (bmffBox, error) {
+	boxes, err := readBoxes(r, start, end)
+	for _, box := range boxes {
+		if box.typ == typ {
+			return box, nil
+		}
+	}
+	if err != nil {
+		return bmffBox{}, err
+	}
+	return bmffBox{}, errBoxNotFound
+}
+
+// readBoxData 讀取 box 的全部內容
+func readBoxData(r io.ReaderAt, box bmffBox, limit int64) ([]byte, error) {
+	if box.size > limit {
+		return nil, fmt.Errorf("box %q 過大: %d", box.typ, box.size)
+	}
+	data := make([]byte, box.size)
+	if _, err := r.ReadAt(data, box.offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readBrands 讀取 ftyp 的主品牌與相容品牌
+func readBrands(r io.ReaderAt, size int64) ([]string, error) {
+	ftyp, err := findBox(r, 0, min(size, 4096), "ftyp")
+	if err != nil {
+		return nil, ErrUnsupportedNative
+	}
+	data, err := readBoxData(r, ftyp, 1024)
+	if err != nil || len(data) < 8 {
+		return nil, ErrUnsupportedNative
+	}
+
+	brands := []string{string(data[:4])}
+	for i := 8; i+4 <= len(data); i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+	return brands, nil
+}
+
+// decodeBMFF 依 ftyp 品牌分派到 CR3、HEIF 或 MP4/MOV 解析器
+func decodeBMFF(r io.ReaderAt, size int64) (*ExifData, error) {
+	brands, err := readBrands(r, size)
+	if err != nil {
+		// 沒有 ftyp 的舊 MOV
+		return decodeQuickTime(r, size)
+	}
+
+	for _, brand := range brands {
+		if brand == "crx " {
+			return decodeCR3(r, size)
+		}
+	}
+	if heifBrands[brands[0]] {
+		return decodeHEIF(r, size)
+	}
+	for _, brand := range brands {
+		if heifBrands[brand] {
+			if data, err := decodeHEIF(r, size); err == nil {
+				return data, nil
+			}
+			break
+		}
+	}
+	return decodeQuickTime(r, size)
+}
+
+// decodeCR3 解析 Canon CR3：moov/uuid 內的 CMT1（IFD0）、CMT2（Exif IFD）、CMT4（GPS IFD）
+func decodeCR3(r io.ReaderAt, size int64) (*ExifData, error) {
+	moov, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return nil, fmt.Errorf("找不到 moov: %v", err)
+	}
+
+	children, err := readBoxes(r, moov.offset, moov.offset+moov.size)
+	if err != nil && len(children) == 0 {
+		return nil, err
+	}
+
+	for _, child := range children {
+		if child.typ != "uuid" || !bytes.Equal(child.uuid, canonCR3UUID) {
+			continue
+		}
+
+		data := &ExifData{}
+		cmts, _ := readBoxes(r, child.offset, child.offset+child.size)
+		for _, cmt := range cmts {
+			var kind ifdKind
+			switch cmt.typ {
+			case "CMT1":
+				kind = ifdKindMain
+			case "CMT2":
+				kind = ifdKindExif
+			case "CMT4":
+				kind = ifdKindGPS
+			default:
+				continue
+			}
+			decodeTIFFInto(data, io.NewSectionReader(r, cmt.offset, cmt.size), cmt.size, kind)
+		}
+		return data, nil
+	}
+
+	return nil, ErrNoExif
+}
+
+// decodeHEIF 從 meta/iinf 找出 Exif 項目，再依 meta/iloc 取得其位置
+func decodeHEIF(r io.ReaderAt, size int64) (*ExifData, error) {
+	meta, err := findBox(r, 0, size, "meta")
+	if err != nil {
+		return nil, fmt.Errorf("找不到 meta: %v", err)
+	}
+	// meta 為 FullBox，內容前 4 個位元組為 version 與 flags
+	if meta.size < 4 {
+		return nil, ErrNoExif
+	}
+	children, err := readBoxes(r, meta.offset+4, meta.offset+meta.size)
+	if err != nil && len(children) == 0 {
+		return nil, err
+	}
+
+	var iinf, iloc, idat *bmffBox
+	for i := range children {
+		switch children[i].typ {
+		case "iinf":
+			iinf = &children[i]
+		case "iloc":
+			iloc = &children[i]
+		case "idat":
+			idat = &children[i]
+		}
+	}
+	if iinf == nil || iloc == nil {
+		return nil, ErrNoExif
+	}
+
+	itemID, err := findExifItem(r, *iinf)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := readItem(r, *iloc, idat, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exif 項目開頭為 4 位元組的 TIFF 標頭位移
+	if len(payload) < 4 {
+		return nil, ErrNoExif
+	}
+	start := 4 + int64(binary.BigEndian.Uint32(payload))
+	if start > int64(len(payload)) {
+		return nil, fmt.Errorf("Exif 項目位移無效")
+	}
+	tiff := bytes.TrimPrefix(payload[start:], exifHeader)
+	return decodeTIFF(bytes.NewReader(tiff), int64(len(tiff)))
+}
+
+// findExifItem 從 iinf 中找出 item_type 為 Exif 的項目編號
+func findExifItem(r io.ReaderAt, iinf bmffBox) (uint32, error) {
+	data, err := readBoxData(r, iinf, 1024*1024)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 6 {
+		return 0, ErrNoExif
+	}
+
+	// iinf FullBox：version 0 的項目數為 16 位元，否則為 32 位元
+	start := int64(6)
+	if data[0] != 0 {
+		start = 8
+	}
+	entries, _ := readBoxes(bytes.NewReader(data), start, int64(len(data)))
+	for _, entry := range entries {
+		if entry.typ != "infe" || entry.size < 4 {
+			continue
+		}
+		infe := data[entry.offset : entry.offset+entry.size]
+		version := infe[0]
+		var id uint32
+		var itemType []byte
+		switch {
+		case version == 2 && len(infe) >= 12:
+			id = uint32(binary.BigEndian.Uint16(infe[4:]))
+			itemType = infe[8:12]
+		case version >= 3 && len(infe) >= 14:
+			id = binary.BigEndian.Uint32(infe[4:])
+			itemType = infe[10:14]
+		default:
+			continue
+		}
+		if string(itemType) == "Exif" {
+			return id, nil
+		}
+	}
+	return 0, ErrNoExif
+}
+
+// readItem 依 iloc 讀取指定項目的內容，支援檔案位移與 idat 兩種建構方式
+func readItem(r io.ReaderAt, iloc bmffBox, idat *bmffBox, itemID uint32) ([]byte, error) {
+	data, err := readBoxData(r, iloc, 1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	p := &byteParser{data: data}
+
+	version := p.uint(1)
+	p.skip(3)
+	sizes := p.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = p.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0F)
+	}
+
+	var itemCount uint64
+	if version < 2 {
+		itemCount = p.uint(2)
+	} else {
+		itemCount = p.uint(4)
+	}
+
+	for i := uint64(0); i < itemCount && p.err == nil; i++ {
+		var id uint64
+		if version < 2 {
+			id = p.uint(2)
+		} else {
+			id = p.uint(4)
+		}
+		constructionMethod := uint64(0)
+		if version == 1 || version == 2 {
+			constructionMethod = p.uint(2) & 0x0F
+		}
+		p.skip(2) // data_reference_index
+		baseOffset := p.uint(baseOffsetSize)
+		extentCount := p.uint(2)
+
+		var payload []byte
+		for j := uint64(0); j < extentCount && p.err == nil; j++ {
+			p.uint(indexSize)
+			extentOffset := p.uint(offsetSize)
+			extentLength := p.uint(lengthSize)
+			if uint32(id) != itemID || p.err != nil {
+				continue
+			}
+
+			offset := int64(baseOffset + extentOffset)
+			if constructionMethod == 1 {
+				if idat == nil {
+					return nil, fmt.Errorf("找不到 idat")
+				}
+				offset += idat.offset
+			} else if constructionMethod != 0 {
+				return nil, fmt.Errorf("不支援的 iloc construction_method: %d", constructionMethod)
+			}
+			if offset < 0 || extentLength > maxExifItemSize || uint64(len(payload))+extentLength > maxExifItemSize {
+				return nil, fmt.Errorf("Exif 項目過大")
+			}
+
+			extent := make([]byte, extentLength)
+			if _, err := r.ReadAt(extent, offset); err != nil {
+				return nil, err
+			}
+			payload = append(payload, extent...)
+		}
+
+		if uint32(id) == itemID && p.err == nil {
+			return payload, nil
+		}
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return nil, ErrNoExif
+}
+
+// byteParser 依序讀取大端序整數，發生越界後所有讀取都回傳 0
+type byteParser struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// uint 讀取 n 個位元組（0~8）的大端序整數
+func (p *byteParser) uint(n int) uint64 {
+	if p.err != nil || n == 0 {
+		return 0
+	}
+	if n > 8 || p.pos+n > len(p.data) {
+		p.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	var v uint64
+	for _, b := range p.data[p.pos : p.pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	p.pos += n
+	return v
+}
+
+// skip 略過 n 個位元組
+func (p *byteParser) skip(n int) {
+	if p.err != nil {
+		return
+	}
+	if p.pos+n > len(p.data) {
+		p.err = io.ErrUnexpectedEOF
+		return
+	}
+	p.pos += n
+}
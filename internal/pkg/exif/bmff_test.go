@@ -0,0 +1,125 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// mkbox 建立一般的 box
+func mkbox(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(8+len(body)))
+	buf.WriteString(typ)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// mkfullbox 建立帶有 version 與 flags 的 FullBox
+func mkfullbox(typ string, version byte, payload ...[]byte) []byte {
+	return mkbox(typ, append([][]byte{{version, 0, 0, 0}}, payload...)...)
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// sampleHEIF 建立 Exif 項目放在 mdat 中的 HEIC 檔
+func sampleHEIF(tiff []byte) []byte {
+	exifItem := append(append(be32(uint32(len(exifHeader))), exifHeader...), tiff...)
+	ftyp := mkbox("ftyp", []byte("heic"), be32(0), []byte("mif1heic"))
+
+	build := func(exifOffset uint32) []byte {
+		infe := mkfullbox("infe", 2, be16(1), be16(0), []byte("Exif"), []byte{0})
+		iinf := mkfullbox("iinf", 0, be16(1), infe)
+		// offset_size=4、length_size=4、base_offset_size=0，一個項目一個 extent
+		iloc := mkfullbox("iloc", 0,
+			[]byte{0x44, 0x00},
+			be16(1),
+			be16(1), be16(0), be16(1),
+			be32(exifOffset), be32(uint32(len(exifItem))),
+		)
+		meta := mkfullbox("meta", 0, mkfullbox("hdlr", 0, be32(0), []byte("pict")), iinf, iloc)
+		return append(append([]byte{}, ftyp...), meta...)
+	}
+
+	head := build(0)
+	head = build(uint32(len(head) + 8))
+	return append(head, mkbox("mdat", exifItem)...)
+}
+
+// sampleCR3 建立 moov/uuid 內含 CMT1、CMT2、CMT4 的 CR3 檔
+func sampleCR3() []byte {
+	order := binary.LittleEndian
+	cmt1 := buildTIFF(order, []testEntry{{tagMake, typeASCII, "Canon"}, {tagModel, typeASCII, "Canon EOS R5"}}, nil, nil)
+	cmt2 := buildTIFF(order, []testEntry{{tagDateTimeOriginal, typeASCII, "2023:11:02 08:09:10"}}, nil, nil)
+	cmt4 := buildTIFF(order, []testEntry{
+		{tagGPSLatitudeRef, typeASCII, "S"},
+		{tagGPSLatitude, typeRational, []uint32{33, 1, 52, 1, 0, 1}},
+		{tagGPSLongitudeRef, typeASCII, "E"},
+		{tagGPSLongitude, typeRational, []uint32{151, 1, 12, 1, 0, 1}},
+	}, nil, nil)
+
+	uuid := mkbox("uuid", canonCR3UUID, mkbox("CNCV", []byte("CanonCR3_001/00.09.00/00.00.00")),
+		mkbox("CMT1", cmt1), mkbox("CMT2", cmt2), mkbox("CMT3", []byte{0}), mkbox("CMT4", cmt4))
+	return append(mkbox("ftyp", []byte("crx "), be32(1), []byte("crx isom")), mkbox("moov", uuid, mkbox("mvhd", make([]byte, 100)))...)
+}
+
+func TestDecodeHEIF(t *testing.T) {
+	file := sampleHEIF(sampleTIFF(binary.BigEndian))
+	data, err := decodeNative(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("解析 HEIF 失敗: %v", err)
+	}
+	if data.Model != "iPhone 11" || data.DateTimeOriginal != "2024:05:03 10:20:30" {
+		t.Errorf("HEIF 資料不符: %+v", data)
+	}
+	if data.GPSLatitude != `25 deg 2' 10.80" N` {
+		t.Errorf("HEIF GPS 不符: %s", data.GPSLatitude)
+	}
+}
+
+func TestDecodeCR3(t *testing.T) {
+	file := sampleCR3()
+	data, err := decodeNative(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("解析 CR3 失敗: %v", err)
+	}
+	if data.Make != "Canon" || data.Model != "Canon EOS R5" {
+		t.Errorf("CR3 裝置不符: %s %s", data.Make, data.Model)
+	}
+	if data.DateTimeOriginal != "2023:11:02 08:09:10" {
+		t.Errorf("CR3 日期不符: %s", data.DateTimeOriginal)
+	}
+	if data.GPSLatitude != `33 deg 52' 0.00" S` || data.GPSLongitude != `151 deg 12' 0.00" E` {
+		t.Errorf("CR3 GPS 不符: %s %s", data.GPSLatitude, data.GPSLongitude)
+	}
+}
+
+func TestDecodeBMFFErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"沒有 Exif 項目的 HEIF", append(mkbox("ftyp", []byte("heic"), be32(0)), mkfullbox("meta", 0, mkfullbox("iinf", 0, be16(0)))...)},
+		{"不支援的品牌", mkbox("ftyp", []byte("abcd"), be32(0))},
+		{"box 大小無效", append(mkbox("ftyp", []byte("heic"), be32(0)), 0, 0, 0, 2, 'm', 'e', 't', 'a')},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeNative(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Error("應回傳錯誤")
+			}
+		})
+	}
+}
+
+func FuzzDecodeBMFF(f *testing.F) {
+	f.Add(sampleHEIF(sampleTIFF(binary.LittleEndian)))
+	f.Add(sampleCR3())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeBMFF(bytes.NewReader(data), int64(len(data)))
+	})
+}
@@ -0,0 +1,233 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+// TimeKind 拍攝時間已知的部分
+type TimeKind int
+
+const (
+	// TimeLocal 只知道拍攝地的當地時間，時區未知（一般照片的 DateTimeOriginal）
+	TimeLocal TimeKind = iota
+	// TimeUTC 只知道 UTC 時間，拍攝地時區未知（例如 MP4/MOV 的 CreateDate）
+	TimeUTC
+	// TimeZoned 同時知道當地時間與時區
+	TimeZoned
+)
+
+// maxZoneOffset 有效的時區位移範圍
+const maxZoneOffset = 14 * time.Hour
+
+// ErrNoCaptureTime 沒有可解析的拍攝時間
+var ErrNoCaptureTime = errors.New("沒有可解析的拍攝時間")
+
+// quickTimeExts 影片的 CreateDate 與 MediaCreateDate 依規格為 UTC
+var quickTimeExts = map[string]bool{
+	".mov": true, ".mp4": true, ".m4v": true, ".3gp": true, ".3g2": true, ".qt": true,
+}
+
+// CaptureTime 解析後的拍攝時間
+type CaptureTime struct {
+	// Time 拍攝時間；TimeLocal 時 Location 為 UTC，但數值即為拍攝地的當地時間
+	Time time.Time
+	Kind TimeKind
+	// Field 使用的時間欄位，例如 DateTimeOriginal
+	Field string
+}
+
+// ResolveCaptureTime 依 DateTimeOriginal、CreationDate、CreateDate、MediaCreateDate 的順序
+// 取得第一個可解析的拍攝時間，並以 OffsetTimeOriginal、日期字串中的時區或 GPS 時間推算時區
+func (e *ExifData) ResolveCaptureTime() (CaptureTime, error) {
+	video := quickTimeExts[strings.ToLower(filepath.Ext(e.SourceFile))]
+
+	if t, zoned, err := parseExifTimeZone(e.DateTimeOriginal); err == nil {
+		if ns, err := parseSubSec(e.SubSecTimeOriginal); err == nil {
+			t = t.Add(ns)
+		}
+		if zoned {
+			return CaptureTime{Time: t, Kind: TimeZoned, Field: "DateTimeOriginal"}, nil
+		}
+		if offset, err := parseZoneOffset(e.OffsetTimeOriginal); err == nil {
+			return CaptureTime{Time: withOffset(t, offset), Kind: TimeZoned, Field: "DateTimeOriginal"}, nil
+		}
+		if offset, ok := e.gpsZoneOffset(t); ok {
+			return CaptureTime{Time: withOffset(t, offset), Kind: TimeZoned, Field: "DateTimeOriginal"}, nil
+		}
+		return CaptureTime{Time: t, Kind: TimeLocal, Field: "DateTimeOriginal"}, nil
+	}
+
+	fields := []struct {
+		name  string
+		value string
+		utc   bool
+	}{
+		{"CreationDate", e.CreationDate, false},
+		{"CreateDate", e.CreateDate, video},
+		{"MediaCreateDate", e.MediaCreateDate, true},
+	}
+	for _, field := range fields {
+		t, zoned, err := parseExifTimeZone(field.value)
+		if err != nil {
+			continue
+		}
+		switch {
+		case zoned:
+			return CaptureTime{Time: t, Kind: TimeZoned, Field: field.name}, nil
+		case field.utc:
+			return CaptureTime{Time: t, Kind: TimeUTC, Field: field.name}, nil
+		default:
+			return CaptureTime{Time: t, Kind: TimeLocal, Field: field.name}, nil
+		}
+	}
+	return CaptureTime{}, ErrNoCaptureTime
+}
+
+// Local 拍攝地的當地時間；只知道 UTC 時拍攝地時區未知，直接使用 UTC 時間，結果不受本機時區影響
+func (c CaptureTime) Local() time.Time {
+	if c.Kind == TimeUTC {
+		return c.Time.UTC()
+	}
+	return c.Time
+}
+
+// In 轉換到指定時區；不知道時區的當地時間視為 loc 的時間
+func (c CaptureTime) In(loc *time.Location) time.Time {
+	if c.Kind == TimeLocal {
+		return time.Date(c.Time.Year(), c.Time.Month(), c.Time.Day(), c.Time.Hour(), c.Time.Minute(), c.Time.Second(), c.Time.Nanosecond(), loc)
+	}
+	return c.Time.In(loc)
+}
+
+// WithLocation 以拍攝地的時區補上未知的時區：當地時間標上時區，UTC 時間轉換成當地時間
+func (c CaptureTime) WithLocation(loc *time.Location) CaptureTime {
+	if c.Kind == TimeZoned {
+		return c
+	}
+	return CaptureTime{Time: c.In(loc), Kind: TimeZoned, Field: c.Field}
+}
+
+// FolderTime 分類資料夾使用的時間：folder_time_zone 未設定時為拍攝地的當地時間（時區未知的 UTC 時間維持 UTC），
+// 否則轉換到該時區
+func (c CaptureTime) FolderTime(cfg *config.Config) time.Time {
+	if loc := cfg.FolderLocation(); loc != nil {
+		return c.In(loc)
+	}
+	return c.Local()
+}
+
+// gpsZoneOffset 以 GPS 的 UTC 時間推算當地時間的時區位移，位移取整到 15 分鐘
+func (e *ExifData) gpsZoneOffset(local time.Time) (time.Duration, bool) {
+	if e.GPSDateStamp == "" || e.GPSTimeStamp == "" {
+		return 0, false
+	}
+	utc, _, err := parseExifTimeZone(e.GPSDateStamp + " " + e.GPSTimeStamp)
+	if err != nil {
+		return 0, false
+	}
+
+	offset := local.Sub(utc).Round(15 * time.Minute)
+	if offset < -maxZoneOffset || offset > maxZoneOffset {
+		return 0, false
+	}
+	return offset, true
+}
+
+// parseExifTimeZone 解析 exiftool 格式的時間，可帶毫秒與 "Z"、"+08:00"、"+0800" 時區，
+// 沒有時區時回傳的時間 Location 為 UTC，zoned 為 false
+func parseExifTimeZone(value string) (t time.Time, zoned bool, err error) {
+	value = strings.TrimSpace(value)
+	if len(value) < len(exifTimeLayout) {
+		return time.Time{}, false, ErrNoCaptureTime
+	}
+	t, err = time.Parse(exifTimeLayout, value[:len(exifTimeLayout)])
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	rest := value[len(exifTimeLayout):]
+	if strings.HasPrefix(rest, ".") {
+		end := 1
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+		if ns, err := parseSubSec(rest[1:end]); err == nil {
+			t = t.Add(ns)
+		}
+		rest = rest[end:]
+	}
+	if rest == "" {
+		return t, false, nil
+	}
+
+	offset, err := parseZoneOffset(rest)
+	if err != nil {
+		// 無法辨識的後綴當成沒有時區
+		return t, false, nil
+	}
+	return withOffset(t, offset), true, nil
+}
+
+// parseZoneOffset 解析 "Z"、"+08:00"、"+0800" 格式的時區位移
+func parseZoneOffset(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "Z" {
+		return 0, nil
+	}
+	if len(value) < 3 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("無效的時區位移: %s", value)
+	}
+
+	digits := strings.ReplaceAll(value[1:], ":", "")
+	if len(digits) != 2 && len(digits) != 4 {
+		return 0, fmt.Errorf("無效的時區位移: %s", value)
+	}
+	hours, err := strconv.Atoi(digits[:2])
+	if err != nil {
+		return 0, fmt.Errorf("無效的時區位移: %s", value)
+	}
+	minutes := 0
+	if len(digits) == 4 {
+		if minutes, err = strconv.Atoi(digits[2:]); err != nil || minutes >= 60 {
+			return 0, fmt.Errorf("無效的時區位移: %s", value)
+		}
+	}
+
+	offset := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if offset > maxZoneOffset {
+		return 0, fmt.Errorf("無效的時區位移: %s", value)
+	}
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// parseSubSec 將 SubSecTimeOriginal 的小數位數轉成時間長度，例如 "52" 為 0.52 秒
+func parseSubSec(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > 9 {
+		return 0, ErrNoCaptureTime
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, ErrNoCaptureTime
+	}
+	for i := len(value); i < 9; i++ {
+		n *= 10
+	}
+	return time.Duration(n), nil
+}
+
+// withOffset 將當地時間 t 標上固定的時區位移
+func withOffset(t time.Time, offset time.Duration) time.Time {
+	zone := time.FixedZone("", int(offset/time.Second))
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), zone)
+}
@@ -0,0 +1,162 @@
+package exif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+func TestResolveCaptureTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     ExifData
+		zone     string
+		kind     TimeKind
+		expected string
+	}{
+		{
+			name:     "沒有時區的照片使用拍攝地時間",
+			data:     ExifData{DateTimeOriginal: "2024:01:01 00:30:00"},
+			kind:     TimeLocal,
+			expected: "2024-01-01 00:30:00.000",
+		},
+		{
+			name:     "沒有時區的照片在固定時區中不轉換",
+			data:     ExifData{DateTimeOriginal: "2024:01:01 00:30:00"},
+			zone:     "UTC",
+			kind:     TimeLocal,
+			expected: "2024-01-01 00:30:00.000",
+		},
+		{
+			name:     "OffsetTimeOriginal 轉換到 UTC",
+			data:     ExifData{DateTimeOriginal: "2024:01:01 02:00:00", OffsetTimeOriginal: "+09:00", SubSecTimeOriginal: "52"},
+			zone:     "UTC",
+			kind:     TimeZoned,
+			expected: "2023-12-31 17:00:00.520",
+		},
+		{
+			name:     "以 GPS 時間推算時區",
+			data:     ExifData{DateTimeOriginal: "2024:01:01 01:30:00", GPSDateStamp: "2023:12:31", GPSTimeStamp: "16:29:58"},
+			zone:     "Europe/London",
+			kind:     TimeZoned,
+			expected: "2023-12-31 16:30:00.000",
+		},
+		{
+			name:     "影片的 CreateDate 為 UTC",
+			data:     ExifData{SourceFile: "/src/VID_0001.MP4", CreateDate: "2024:01:01 20:00:00"},
+			zone:     "Asia/Taipei",
+			kind:     TimeUTC,
+			expected: "2024-01-02 04:00:00.000",
+		},
+		{
+			name:     "Apple CreationDate 保留時區",
+			data:     ExifData{SourceFile: "/src/IMG_0001.MOV", CreationDate: "2019:02:03 23:10:10+08:00", CreateDate: "2019:02:03 15:10:10"},
+			kind:     TimeZoned,
+			expected: "2019-02-03 23:10:10.000",
+		},
+		{
+			name:     "照片的 CreateDate 為當地時間",
+			data:     ExifData{SourceFile: "/src/IMG_0001.JPG", CreateDate: "2024:01:01 20:00:00"},
+			zone:     "Asia/Taipei",
+			kind:     TimeLocal,
+			expected: "2024-01-01 20:00:00.000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture, err := tt.data.ResolveCaptureTime()
+			if err != nil {
+				t.Fatalf("解析失敗: %v", err)
+			}
+			if capture.Kind != tt.kind {
+				t.Errorf("時間類型不符: 期望 %d，得到 %d", tt.kind, capture.Kind)
+			}
+			got := capture.FolderTime(&config.Config{FolderTimeZone: tt.zone}).Format("2006-01-02 15:04:05.000")
+			if got != tt.expected {
+				t.Errorf("時間不符: 期望 %s，得到 %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseZoneOffset(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"Z", 0, true},
+		{"+08:00", 8 * time.Hour, true},
+		{"-0330", -(3*time.Hour + 30*time.Minute), true},
+		{"+05", 5 * time.Hour, true},
+		{"+15:00", 0, false},
+		{"08:00", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		offset, err := parseZoneOffset(tt.value)
+		if (err == nil) != tt.valid || offset != tt.expected {
+			t.Errorf("%q: 得到 %v, %v", tt.value, offset, err)
+		}
+	}
+}
+
+func TestUnmarshalNumericSubSec(t *testing.T) {
+	var data []ExifData
+	if err := json.Unmarshal([]byte(`[{"SourceFile":"a.jpg","SubSecTimeOriginal":52,"Model":"X"}]`), &data); err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if data[0].SubSecTimeOriginal != "52" || data[0].Model != "X" || data[0].SourceFile != "a.jpg" {
+		t.Errorf("資料不符: %+v", data[0])
+	}
+}
+
+func TestGetTargetPathTimeZoneFromGPS(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "timezones.geojson")
+	content := `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"tzid": "America/Los_Angeles"},
+		"geometry": {"type": "Polygon", "coordinates": [[[-125, 32], [-114, 32], [-114, 42], [-125, 42], [-125, 32]]]}}]}`
+	if err := os.WriteFile(jsonPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	timeZones, err := geocoding.NewGeoTimeZone(jsonPath)
+	if err != nil {
+		t.Fatalf("載入時區邊界失敗: %v", err)
+	}
+	cfg := &config.Config{DstDir: t.TempDir(), DateFormat: "2006-01-02"}
+
+	tests := []struct {
+		name     string
+		data     ExifData
+		expected string
+	}{
+		{
+			name:     "影片的 UTC 時間轉換成拍攝地時間",
+			data:     ExifData{SourceFile: "/src/VID_0001.MP4", CreateDate: "2024:01:01 04:00:00", GPSLatitude: "37 deg 46' 29.64\" N", GPSLongitude: "122 deg 25' 9.84\" W"},
+			expected: "2023-12-31",
+		},
+		{
+			name:     "已有時區的時間不重新換算",
+			data:     ExifData{SourceFile: "/src/IMG_0001.JPG", DateTimeOriginal: "2024:01:01 04:00:00", OffsetTimeOriginal: "+09:00", GPSLatitude: "37 deg 46' 29.64\" N", GPSLongitude: "122 deg 25' 9.84\" W"},
+			expected: "2024-01-01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := GetTargetPath(tt.data.SourceFile, &tt.data, cfg, timeZones)
+			if err != nil {
+				t.Fatalf("取得目標路徑失敗: %v", err)
+			}
+			if got := filepath.Base(filepath.Dir(filepath.Dir(target))); got != tt.expected {
+				t.Errorf("日期資料夾不符: 期望 %s，得到 %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package exif
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+// Companion 跟著主要檔案搬移的檔案（例如 Live Photo 的影片、.AAE 編輯記錄），
+// 目標檔名與主要檔案相同，只有副檔名不同
+type Companion struct {
+	Path   string // 原始路徑
+	Subdir string // 相對於主要檔案目標資料夾的子資料夾，空白表示放在同一個資料夾
+}
+
+// CompanionTarget 依主要檔案的目標路徑決定伴隨檔案的目標路徑
+func CompanionTarget(targetPath string, c Companion) string {
+	stem := strings.TrimSuffix(filepath.Base(targetPath), filepath.Ext(targetPath))
+	return filepath.Join(filepath.Dir(targetPath), c.Subdir, stem+filepath.Ext(c.Path))
+}
+
+// available 目標路徑與所有伴隨檔案的目標路徑都還不存在
+func available(targetPath string, companions []Companion) bool {
+	if _, err := os.Stat(targetPath); !os.IsNotExist(err) {
+		return false
+	}
+	for _, c := range companions {
+		if _, err := os.Stat(CompanionTarget(targetPath, c)); !os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindCompanions 找出媒體檔案旁邊副檔名在 companion_extensions 中的伴隨檔案，
+// 例如 IMG_0001.AAE、GOPR0001.THM，以及 GoPro 的低解析度影片 GL010001.LRV（對應 GX010001.MP4）
+func FindCompanions(path string, cfg *config.Config, dirs *DirCache) []string {
+	dir, name := filepath.Split(path)
+	var companions []string
+	for _, candidate := range dirs.list(filepath.Clean(dir)) {
+		if candidate != name && cfg.IsCompanionExt(candidate) && companionOf(candidate, name) {
+			companions = append(companions, filepath.Join(dir, candidate))
+		}
+	}
+	return companions
+}
+
+// IsCompanionFile 判斷檔案是否為旁邊某個要處理的媒體檔的伴隨檔案，這類檔案不單獨處理；
+// 找不到媒體檔的伴隨檔案照一般檔案處理
+func IsCompanionFile(path string, cfg *config.Config, dirs *DirCache) bool {
+	if !cfg.IsCompanionExt(path) {
+		return false
+	}
+	dir, name := filepath.Split(path)
+	for _, candidate := range dirs.list(filepath.Clean(dir)) {
+		if cfg.IsCompanionExt(candidate) || !cfg.IsSupportedFormat(candidate) || cfg.ShouldIgnore(candidate) {
+			continue
+		}
+		if companionOf(name, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// companionOf 伴隨檔案與媒體檔的檔名（不含副檔名）相同，
+// 或是 GoPro 以 GL 開頭的低解析度影片對應 GH、GX 開頭的影片
+func companionOf(companion, media string) bool {
+	c := strings.ToUpper(strings.TrimSuffix(companion, filepath.Ext(companion)))
+	m := strings.ToUpper(strings.TrimSuffix(media, filepath.Ext(media)))
+	if c == m {
+		return true
+	}
+	return len(c) > 2 && strings.HasPrefix(c, "GL") && (strings.HasPrefix(m, "GH") || strings.HasPrefix(m, "GX")) && c[2:] == m[2:]
+}
@@ -0,0 +1,95 @@
+package exif
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestGetTargetPathCompanions(t *testing.T) {
+	srcDir := t.TempDir()
+	path := filepath.Join(srcDir, "IMG_0001.HEIC")
+	cfg := &config.Config{SrcDir: srcDir, DstDir: t.TempDir(), DateFormat: "2006-01"}
+	data := &ExifData{SourceFile: path, DateTimeOriginal: "2024:05:03 10:20:30", Model: "iPhone 15 Pro"}
+	motion := Companion{Path: filepath.Join(srcDir, "IMG_0001.MOV"), Subdir: "live"}
+
+	target, err := GetTargetPath(path, data, cfg, nil, motion)
+	if err != nil {
+		t.Fatalf("取得目標路徑失敗: %v", err)
+	}
+	expected := filepath.Join(cfg.DstDir, "2024-05", "iPhone_15_Pro", "live", "IMG_0001.MOV")
+	if got := CompanionTarget(target, motion); got != expected {
+		t.Errorf("影片目標路徑 = %s, want %s", got, expected)
+	}
+
+	// 只有影片的目標檔名被占用時，照片也要換名字，讓兩者維持相同檔名
+	if err := os.MkdirAll(filepath.Dir(expected), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(expected, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	target, err = GetTargetPath(path, data, cfg, nil, motion)
+	if err != nil {
+		t.Fatalf("取得目標路徑失敗: %v", err)
+	}
+	if filepath.Base(target) != "IMG_0001_1.HEIC" {
+		t.Errorf("照片目標檔名 = %s, want IMG_0001_1.HEIC", filepath.Base(target))
+	}
+	if got := filepath.Base(CompanionTarget(target, motion)); got != "IMG_0001_1.MOV" {
+		t.Errorf("影片目標檔名 = %s, want IMG_0001_1.MOV", got)
+	}
+}
+
+func TestFindCompanions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"IMG_0001.HEIC", "IMG_0001.AAE", "IMG_0002.AAE",
+		"GX010123.MP4", "GL010123.LRV", "GX010123.THM",
+		"DJI_0001.MP4", "DJI_0001.SRT", "DJI_0001.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{
+		Formats:             []string{".heic", ".mp4"},
+		CompanionExtensions: []string{".aae", ".thm", ".lrv", ".srt"},
+	}
+	dirs := NewDirCache()
+
+	tests := []struct {
+		media    string
+		expected []string
+	}{
+		{"IMG_0001.HEIC", []string{"IMG_0001.AAE"}},
+		{"GX010123.MP4", []string{"GL010123.LRV", "GX010123.THM"}},
+		{"DJI_0001.MP4", []string{"DJI_0001.SRT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.media, func(t *testing.T) {
+			var got []string
+			for _, companion := range FindCompanions(filepath.Join(dir, tt.media), cfg, dirs) {
+				got = append(got, filepath.Base(companion))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("FindCompanions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	companions := map[string]bool{
+		"IMG_0001.AAE": true,
+		"GL010123.LRV": true,
+		"IMG_0002.AAE": false, // 沒有對應的媒體檔，照一般檔案處理
+		"DJI_0001.txt": false, // 不在 companion_extensions 中
+	}
+	for name, expected := range companions {
+		if got := IsCompanionFile(filepath.Join(dir, name), cfg, dirs); got != expected {
+			t.Errorf("IsCompanionFile(%s) = %v, want %v", name, got, expected)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package exif
+
+import (
+	"os"
+	"sync"
+)
+
+// DirCache 資料夾中檔名的快取，尋找 sidecar 與伴隨檔案時共用，只在一次執行中使用，
+// 執行期間來源資料夾不會變動；nil 表示不快取，每次都讀取資料夾
+type DirCache struct {
+	names sync.Map
+}
+
+// NewDirCache 建立空的資料夾快取
+func NewDirCache() *DirCache {
+	return &DirCache{}
+}
+
+// list 取得資料夾中所有檔案名稱
+func (c *DirCache) list(dir string) []string {
+	if c != nil {
+		if names, ok := c.names.Load(dir); ok {
+			return names.([]string)
+		}
+	}
+
+	var names []string
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	if c != nil {
+		c.names.Store(dir, names)
+	}
+	return names
+}
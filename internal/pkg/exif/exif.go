@@ -12,14 +12,111 @@ import (
 
 	"photo-sorter/internal/pkg/config"
 	"photo-sorter/internal/pkg/geocoding"
+	"photo-sorter/internal/pkg/layout"
 )
 
 type ExifData struct {
-	CreateDate      string `json:"CreateDate"`
-	MediaCreateDate string `json:"MediaCreateDate"`
-	Model           string `json:"Model"`
-	GPSLatitude     string `json:"GPSLatitude"`
-	GPSLongitude    string `json:"GPSLongitude"`
+	SourceFile         string `json:"SourceFile"`
+	DateTimeOriginal   string `json:"DateTimeOriginal"`
+	OffsetTimeOriginal string `json:"OffsetTimeOriginal"`
+	SubSecTimeOriginal string `json:"SubSecTimeOriginal"`
+	CreationDate       string `json:"CreationDate"`
+	CreateDate         string `json:"CreateDate"`
+	MediaCreateDate    string `json:"MediaCreateDate"`
+	Make               string `json:"Make"`
+	Model              string `json:"Model"`
+	SerialNumber       string `json:"SerialNumber"`
+	LensModel          string `json:"LensModel"`
+	ImageWidth         int    `json:"ImageWidth"`
+	ImageHeight        int    `json:"ImageHeight"`
+	SequenceNumber     int    `json:"SequenceNumber"`
+	ContentIdentifier  string `json:"ContentIdentifier"` // Live Photo 的照片與影片共用的識別碼
+	BurstUUID          string `json:"BurstUUID"`         // iPhone 同一次連拍共用的識別碼
+	GPSLatitude        string `json:"GPSLatitude"`
+	GPSLongitude       string `json:"GPSLongitude"`
+	GPSDateStamp       string `json:"GPSDateStamp"`
+	GPSTimeStamp       string `json:"GPSTimeStamp"`
+
+	// Rating 與 Keywords 目前只從 XMP 取得，-1 表示被標記為拒絕
+	Rating   int      `json:"-"`
+	Keywords []string `json:"-"`
+
+	// DatePattern 日期由檔名推測時符合的檔名規則
+	DatePattern string `json:"-"`
+	// TimeShift 依 time_corrections 修正拍攝時間時加上的位移
+	TimeShift time.Duration `json:"-"`
+	// ZoneUnknown 只知道 UTC 時間且無法得知拍攝地時區，分類資料夾以 UTC 的日期決定
+	ZoneUnknown bool `json:"-"`
+	// BurstDir 連拍分組時設定的子資料夾名稱，例如 burst_20240503_102030
+	BurstDir string `json:"-"`
+	// Event 事件分群時設定的事件名稱，例如 2024-05-03_to_2024-05-09-JPN-Tokyo
+	Event string `json:"-"`
+	// Trip 旅行偵測時設定的旅行名稱，例如 2024-05-Trip-JPN
+	Trip string `json:"-"`
+	// Location 以 Locate 查詢到的地點，路徑與標籤共用同一次查詢的結果
+	Location *geocoding.CountryCity `json:"-"`
+	located  bool
+	// Sources 記錄每個欄位（date、make、model、gps）由哪個擷取器提供
+	Sources map[string]string `json:"-"`
+}
+
+// UnmarshalJSON exiftool -json 會把看起來像數字的值輸出成數字，例如 SubSecTimeOriginal 與 SerialNumber
+func (e *ExifData) UnmarshalJSON(b []byte) error {
+	type plain ExifData
+	aux := struct {
+		*plain
+		Model              json.RawMessage `json:"Model"`
+		SerialNumber       json.RawMessage `json:"SerialNumber"`
+		SubSecTimeOriginal json.RawMessage `json:"SubSecTimeOriginal"`
+		ImageWidth         json.RawMessage `json:"ImageWidth"`
+		ImageHeight        json.RawMessage `json:"ImageHeight"`
+		SequenceNumber     json.RawMessage `json:"SequenceNumber"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	e.Model = jsonString(aux.Model)
+	e.SerialNumber = jsonString(aux.SerialNumber)
+	e.SubSecTimeOriginal = jsonString(aux.SubSecTimeOriginal)
+	e.ImageWidth, _ = strconv.Atoi(jsonString(aux.ImageWidth))
+	e.ImageHeight, _ = strconv.Atoi(jsonString(aux.ImageHeight))
+	// 不是連拍時部分相機會輸出 "Single"
+	e.SequenceNumber, _ = strconv.Atoi(jsonString(aux.SequenceNumber))
+	return nil
+}
+
+// jsonString 將 JSON 字串或數字轉成字串，null 或空值為空字串
+func jsonString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return string(raw)
+	}
+	return s
+}
+
+// captureDate 依優先順序取得日期：拍攝時間、Apple 影片的本地建立時間、建立時間、媒體建立時間
+func (e *ExifData) captureDate() string {
+	for _, date := range []string{e.DateTimeOriginal, e.CreationDate, e.CreateDate, e.MediaCreateDate} {
+		if date != "" {
+			return date
+		}
+	}
+	return ""
+}
+
+// exifTimeLayout exiftool 輸出的時間格式
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// parseExifTime 解析 exiftool 格式的時間，忽略後面的時區或毫秒
+func parseExifTime(value string) (time.Time, error) {
+	if len(value) > len(exifTimeLayout) {
+		value = value[:len(exifTimeLayout)]
+	}
+	return time.Parse(exifTimeLayout, value)
 }
 
 // ParseGPSString 將 GPS 字串轉換為浮點數
@@ -71,9 +168,51 @@ func ParseGPSString(gpsStr string) (float64, error) {
 	return decimal, nil
 }
 
+// Coordinates 取得十進位的經緯度，沒有 GPS 資訊或無法解析時 ok 為 false
+func (e *ExifData) Coordinates() (lat, lon float64, ok bool) {
+	if e.GPSLatitude == "" || e.GPSLongitude == "" {
+		return 0, 0, false
+	}
+	lat, err := ParseGPSString(e.GPSLatitude)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err = ParseGPSString(e.GPSLongitude)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lon, lat != 0 || lon != 0
+}
+
+// Locate 以 geocoder 查詢 GPS 所在的地點並保存在 Location，每個檔案只查詢一次；
+// 沒有 GPS 或查不到地點時回傳 nil，GPS 無法解析時回傳錯誤
+func (e *ExifData) Locate(geocoder geocoding.Geocoder) (*geocoding.CountryCity, error) {
+	if e.located || geocoder == nil || e.GPSLatitude == "" || e.GPSLongitude == "" {
+		return e.Location, nil
+	}
+
+	lat, err := ParseGPSString(e.GPSLatitude)
+	if err != nil {
+		return nil, fmt.Errorf("解析緯度失敗: %v", err)
+	}
+	lon, err := ParseGPSString(e.GPSLongitude)
+	if err != nil {
+		return nil, fmt.Errorf("解析經度失敗: %v", err)
+	}
+
+	e.located = true
+	if lat != 0 && lon != 0 {
+		if location, err := geocoder.GetLocationFromGPS(lat, lon); err == nil {
+			e.Location = location
+		}
+	}
+	return e.Location, nil
+}
+
+// GetExifData 單次啟動 exiftool 取得 EXIF 資料
 func GetExifData(path string) (*ExifData, error) {
 	startTime := time.Now()
-	cmd := exec.Command("exiftool", "-json", "-CreateDate", "-MediaCreateDate", "-Model", "-GPSLatitude", "-GPSLongitude", path)
+	cmd := exec.Command("exiftool", exiftoolArgs(path)...)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("執行 exiftool 失敗: %v", err)
@@ -97,84 +236,180 @@ func GetExifData(path string) (*ExifData, error) {
 	return &data[0], nil
 }
 
-func GetTargetPath(path string, exif *ExifData, cfg *config.Config) (string, error) {
-	// 取得日期
-	date := exif.CreateDate
-	if date == "" {
-		date = exif.MediaCreateDate
-	}
-	if date == "" {
-		date = "unknown_date"
-	} else {
-		// 解析日期字串並使用設定檔中的格式
-		t, err := time.Parse("2006:01:02 15:04:05", date)
-		if err != nil {
-			date = "unknown_date"
-		} else {
-			date = t.Format(cfg.DateFormat)
+// FolderTime 分類使用的拍攝時間：沒有可用的時間時從檔名推測，修正相機時鐘的誤差，
+// 沒有時區時以 timeZones 查詢 GPS 所在地的時區換算，再依 folder_time_zone 轉換；沒有拍攝時間時 ok 為 false。
+// timeZones 為 nil 時不查詢時區；只知道 UTC 時間又無法決定時區時使用 UTC，並記錄在 ZoneUnknown
+func (e *ExifData) FolderTime(path string, cfg *config.Config, timeZones geocoding.TimeZoneGeocoder) (captured time.Time, ok bool) {
+	capture, err := e.ResolveCaptureTime()
+	if err != nil {
+		if _, err = e.applyFilenameDate(path, cfg.FilenamePatterns); err != nil {
+			return time.Time{}, false
+		}
+		if capture, err = e.ResolveCaptureTime(); err != nil {
+			return time.Time{}, false
 		}
 	}
 
-	// 如果有啟用地理位置標籤且有 GPS 資訊，則加入地理位置
-	if cfg.EnableGeoTag && exif.GPSLatitude != "" && exif.GPSLongitude != "" {
-		lat, err := ParseGPSString(exif.GPSLatitude)
-		if err != nil {
-			return "", fmt.Errorf("解析緯度失敗: %v", err)
+	// 修正相機時鐘的誤差
+	capture = e.CorrectCaptureTime(capture, cfg)
+	// 沒有時區的時間以 GPS 所在地的時區換算
+	if capture.Kind != TimeZoned && timeZones != nil {
+		if lat, lon, ok := e.Coordinates(); ok {
+			if loc, err := geocoding.LoadLocationFromGPS(timeZones, lat, lon); err == nil {
+				capture = capture.WithLocation(loc)
+			}
 		}
+	}
+	e.ZoneUnknown = capture.Kind == TimeUTC && cfg.FolderLocation() == nil
+	// 依設定的時區決定分類使用的時間
+	return capture.FolderTime(cfg), true
+}
 
-		lon, err := ParseGPSString(exif.GPSLongitude)
-		if err != nil {
-			return "", fmt.Errorf("解析經度失敗: %v", err)
-		}
+// GetTargetPath 依 layout_rules 或 path_template 決定目標路徑，檔名重複時加上 _1、_2 等編號，
+// companions 的目標檔名與主要檔案相同，決定編號時會一起檢查，確保整組檔案使用相同的檔名；
+// 地理位置使用 Locate 查詢過的 Location，timeZones 用於查詢沒有時區的拍攝時間所在的時區
+func GetTargetPath(path string, exif *ExifData, cfg *config.Config, timeZones geocoding.TimeZoneGeocoder, companions ...Companion) (string, error) {
+	captured, _ := exif.FolderTime(path, cfg, timeZones)
 
-		if lat != 0 && lon != 0 {
-			geocoder, err := geocoding.NewGeocoder(cfg.GeocoderType, map[string]interface{}{
-				"json_path": cfg.GeoJSONPath,
-			})
-			if err == nil {
-				countryCity, err := geocoder.GetLocationFromGPS(lat, lon)
-				if err == nil && countryCity != nil {
-					date = fmt.Sprintf("%s-%s-%s", date, countryCity.Country, strings.ReplaceAll(countryCity.City, " ", "_"))
-				}
-			}
-		}
+	// 依 layout_rules 選擇目錄結構與日期格式
+	info := &mediaInfo{path: path, data: exif}
+	if rel, err := filepath.Rel(cfg.SrcDir, filepath.Dir(path)); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		info.dir = rel
+	}
+	tmpl, dateFormat, err := cfg.SelectLayout(info)
+	if err != nil {
+		return "", err
 	}
 
-	// 取得裝置名稱
-	device := exif.Model
-	if device == "" {
-		device = "unknown_device"
-	} else {
-		// 處理裝置名稱
-		device = strings.ReplaceAll(device, " ", "_")
-		device = strings.Map(func(r rune) rune {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
-				return r
-			}
-			return -1
-		}, device)
+	fields := layout.NewFields(path, captured, dateFormat)
+	fields.Device = deviceName(exif.Model)
+	fields.Make, fields.Model, fields.Lens = exif.Make, exif.Model, exif.LensModel
+	fields.Kind, fields.Dir = info.Kind(), info.dir
+	fields.Event, fields.Trip = exif.Event, exif.Trip
+	if exif.SequenceNumber > 0 {
+		fields.Seq = fmt.Sprintf("_%03d", exif.SequenceNumber)
+	}
+
+	// 如果有啟用地理位置標籤且已查詢到地點，則加入地理位置
+	if cfg.EnableGeoTag && exif.Location != nil {
+		fields.Country, fields.Region, fields.City = exif.Location.Country, exif.Location.Region, exif.Location.FormatCity()
+		fields.Place = exif.Location.Place
+	}
+
+	// 依樣板產生目標路徑
+	rel, err := tmpl.Execute(fields)
+	if err != nil {
+		return "", err
+	}
+	targetPath := filepath.Join(cfg.DstDir, rel)
+
+	// 連拍放到目標資料夾下的子資料夾
+	if exif.BurstDir != "" {
+		targetPath = filepath.Join(filepath.Dir(targetPath), exif.BurstDir, filepath.Base(targetPath))
+	}
+
+	// 依檔名樣板重新命名，保留原始副檔名
+	rename, err := cfg.Rename()
+	if err != nil {
+		return "", err
+	}
+	if rename != nil {
+		name, err := rename.Execute(fields)
+		if err != nil {
+			return "", err
+		}
+		targetPath = filepath.Join(filepath.Dir(targetPath), name+fields.Ext)
 	}
 
-	// 建立目標路徑
-	targetDir := filepath.Join(cfg.DstDir, date, device)
+	// 建立目標資料夾
+	targetDir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		return "", fmt.Errorf("建立目標資料夾失敗: %v", err)
 	}
+	if rename != nil {
+		return renamedTargetPath(path, targetPath, fields, companions)
+	}
 
 	// 處理檔案名稱衝突
-	baseName := filepath.Base(path)
+	baseName := filepath.Base(targetPath)
 	ext := filepath.Ext(baseName)
 	nameWithoutExt := strings.TrimSuffix(baseName, ext)
-	targetPath := filepath.Join(targetDir, baseName)
 
 	counter := 1
-	for {
-		if _, err := os.Stat(targetPath); os.IsNotExist(err) {
-			break
-		}
+	for !available(targetPath, companions) {
 		targetPath = filepath.Join(targetDir, fmt.Sprintf("%s_%d%s", nameWithoutExt, counter, ext))
 		counter++
 	}
 
 	return targetPath, nil
 }
+
+// renamedTargetPath 處理重新命名後的檔名衝突：內容相同的檔案沿用同一個檔名，
+// 內容不同時加上內容雜湊而不是依處理順序編號，讓重新執行時同一個檔案得到相同的檔名
+func renamedTargetPath(path, targetPath string, fields *layout.Fields, companions []Companion) (string, error) {
+	ext := filepath.Ext(targetPath)
+	stem := strings.TrimSuffix(targetPath, ext)
+
+	candidate := targetPath
+	for i := 0; i < 2; i++ {
+		if available(candidate, companions) {
+			return candidate, nil
+		}
+		same, err := sameContent(path, candidate, fields)
+		if err != nil {
+			return "", err
+		}
+		if same {
+			return candidate, nil
+		}
+
+		hash, err := fields.Hash(8)
+		if err != nil {
+			return "", fmt.Errorf("計算檔案雜湊失敗: %v", err)
+		}
+		candidate = fmt.Sprintf("%s_%s%s", stem, hash, ext)
+	}
+
+	// 雜湊前綴也相同但內容不同時，退回依序編號
+	counter := 1
+	for {
+		next := fmt.Sprintf("%s_%d%s", strings.TrimSuffix(candidate, ext), counter, ext)
+		if available(next, companions) {
+			return next, nil
+		}
+		counter++
+	}
+}
+
+// sameContent 比較原始檔案與已存在的目標檔案內容是否相同
+func sameContent(path, target string, fields *layout.Fields) (bool, error) {
+	src, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	dst, err := os.Stat(target)
+	if err != nil || src.Size() != dst.Size() {
+		return false, nil
+	}
+
+	srcHash, err := fields.Hash(0)
+	if err != nil {
+		return false, fmt.Errorf("計算檔案雜湊失敗: %v", err)
+	}
+	dstHash, err := layout.NewFields(target, time.Time{}, "").Hash(0)
+	if err != nil {
+		return false, fmt.Errorf("計算檔案雜湊失敗: %v", err)
+	}
+	return srcHash == dstHash, nil
+}
+
+// deviceName 將相機型號轉成資料夾名稱，空白換成底線並只保留英數字與底線
+func deviceName(model string) string {
+	model = strings.ReplaceAll(model, " ", "_")
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return -1
+	}, model)
+}
@@ -0,0 +1,403 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// exiftoolTags 查詢時要求 exiftool 輸出的欄位
+var exiftoolTags = []string{
+	"-json",
+	"-DateTimeOriginal", "-OffsetTimeOriginal", "-SubSecTimeOriginal",
+	"-CreationDate", "-CreateDate", "-MediaCreateDate",
+	"-Make", "-Model", "-SerialNumber", "-LensModel",
+	"-ImageWidth", "-ImageHeight", "-SequenceNumber", "-ContentIdentifier", "-BurstUUID",
+	"-GPSLatitude", "-GPSLongitude", "-GPSDateStamp", "-GPSTimeStamp",
+}
+
+// exiftoolArgs 組合查詢參數與檔案路徑
+func exiftoolArgs(paths ...string) []string {
+	args := make([]string, 0, len(exiftoolTags)+len(paths))
+	args = append(args, exiftoolTags...)
+	return append(args, paths...)
+}
+
+// ErrExifToolClosed exiftool 常駐程序已關閉
+var ErrExifToolClosed = errors.New("exiftool 常駐程序已關閉")
+
+// ExifTool 管理多個 `exiftool -stay_open True -@ -` 常駐程序，
+// 讓 worker pool 共用，避免每個檔案都重新啟動一次 exiftool
+type ExifTool struct {
+	slots chan *exiftoolSlot
+	all   []*exiftoolSlot
+	seq   atomic.Uint64
+	mu    sync.Mutex // 保護 slot.proc 的替換與關閉
+	done  chan struct{}
+	once  sync.Once
+}
+
+// exiftoolSlot 常駐程序的槽位，程序崩潰後會在同一個槽位重新啟動
+type exiftoolSlot struct {
+	proc *exiftoolProcess
+}
+
+// exiftoolProcess 單一 exiftool 常駐程序
+type exiftoolProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr <-chan stderrSegment // 由 readStderr 依 -echo4 標記切好的錯誤輸出
+}
+
+// stderrSegment 單次執行的錯誤輸出，marker 為該次執行的結尾標記
+type stderrSegment struct {
+	marker string
+	data   []byte
+}
+
+// NewExifTool 啟動 n 個 exiftool 常駐程序，ctx 取消時會自動關閉
+func NewExifTool(ctx context.Context, n int) (*ExifTool, error) {
+	if n <= 0 {
+		n = 1
+	}
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		return nil, fmt.Errorf("找不到 exiftool: %v", err)
+	}
+
+	e := &ExifTool{
+		slots: make(chan *exiftoolSlot, n),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < n; i++ {
+		proc, err := startExifToolProcess()
+		if err != nil {
+			e.Close()
+			return nil, fmt.Errorf("啟動 exiftool 失敗: %v", err)
+		}
+		slot := &exiftoolSlot{proc: proc}
+		e.all = append(e.all, slot)
+		e.slots <- slot
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			e.Close()
+		case <-e.done:
+		}
+	}()
+
+	return e, nil
+}
+
+// GetExifData 透過常駐程序取得單一檔案的 EXIF 資料
+func (e *ExifTool) GetExifData(path string) (*ExifData, error) {
+	startTime := time.Now()
+	output, err := e.execute(exiftoolArgs(path)...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parseExifToolOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	// 記錄執行時間
+	executionTime := time.Since(startTime)
+	if executionTime > 1*time.Second {
+		fmt.Printf("警告: exiftool 處理檔案 %s 耗時 %.2f 秒\n", path, executionTime.Seconds())
+	}
+
+	return &data[0], nil
+}
+
+// GetExifDataBatch 以單次 exiftool -json 呼叫取得多個檔案的 EXIF 資料，
+// 回傳的 map 只包含成功取得資料的檔案，缺少的檔案即為處理失敗
+func (e *ExifTool) GetExifDataBatch(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	startTime := time.Now()
+	output, err := e.execute(exiftoolArgs(paths...)...)
+	if err != nil {
+		if errors.Is(err, ErrExifToolClosed) {
+			return nil, err
+		}
+		// 整批失敗（例如某個檔案讓 exiftool 崩潰），改為逐一查詢以找出失敗的檔案
+		for _, path := range paths {
+			data, err := e.GetExifData(path)
+			if errors.Is(err, ErrExifToolClosed) {
+				return result, err
+			}
+			if err == nil {
+				result[path] = data
+			}
+		}
+		return result, nil
+	}
+
+	// 所有檔案都失敗時 exiftool 不會輸出 JSON
+	if len(bytes.TrimSpace(output.stdout)) == 0 {
+		return result, nil
+	}
+
+	var data []ExifData
+	if err := json.Unmarshal(output.stdout, &data); err != nil {
+		return nil, fmt.Errorf("解析 exiftool 輸出失敗: %v", err)
+	}
+
+	// 依 SourceFile 對應回原始路徑
+	requested := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		requested[path] = true
+	}
+	for i := range data {
+		source := data[i].SourceFile
+		if !requested[source] {
+			source = filepath.FromSlash(source)
+		}
+		if requested[source] {
+			result[source] = &data[i]
+		}
+	}
+
+	// 記錄執行時間
+	executionTime := time.Since(startTime)
+	if executionTime > time.Duration(len(paths))*time.Second {
+		fmt.Printf("警告: exiftool 批次處理 %d 個檔案耗時 %.2f 秒\n", len(paths), executionTime.Seconds())
+	}
+
+	return result, nil
+}
+
+// Close 關閉所有常駐程序，可重複呼叫
+func (e *ExifTool) Close() error {
+	e.once.Do(func() {
+		close(e.done)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		for _, slot := range e.all {
+			if slot.proc != nil {
+				slot.proc.stop()
+			}
+		}
+	})
+	return nil
+}
+
+// execute 取得一個常駐程序執行一次指令，程序崩潰時重新啟動並重試一次
+func (e *ExifTool) execute(args ...string) (*exiftoolOutput, error) {
+	var slot *exiftoolSlot
+	select {
+	case <-e.done:
+		return nil, ErrExifToolClosed
+	case slot = <-e.slots:
+	}
+	defer func() { e.slots <- slot }()
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		proc, err := e.ensureProcess(slot)
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := proc.execute(e.seq.Add(1), args)
+		if err == nil {
+			return output, nil
+		}
+		lastErr = err
+
+		// 程序已無法使用，關閉後下一輪會重新啟動
+		e.mu.Lock()
+		proc.kill()
+		slot.proc = nil
+		e.mu.Unlock()
+	}
+
+	return nil, fmt.Errorf("exiftool 常駐程序異常: %v", lastErr)
+}
+
+// ensureProcess 確保槽位中有可用的程序
+func (e *ExifTool) ensureProcess(slot *exiftoolSlot) (*exiftoolProcess, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return nil, ErrExifToolClosed
+	default:
+	}
+
+	if slot.proc == nil {
+		proc, err := startExifToolProcess()
+		if err != nil {
+			return nil, fmt.Errorf("重新啟動 exiftool 失敗: %v", err)
+		}
+		slot.proc = proc
+	}
+	return slot.proc, nil
+}
+
+// startExifToolProcess 啟動 exiftool 常駐程序，從 stdin 讀取參數
+func startExifToolProcess() (*exiftoolProcess, error) {
+	cmd := exec.Command("exiftool", "-stay_open", "True", "-@", "-")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	segments := make(chan stderrSegment, 1)
+	go readStderr(bufio.NewReader(stderr), segments)
+
+	return &exiftoolProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		stderr: segments,
+	}, nil
+}
+
+// readStderr 在程序啟動後持續讀取 stderr，依 -echo4 輸出的 {readyN} 標記切成每次執行的錯誤輸出。
+// stderr 必須與 stdout 同時讀取，否則大量損毀檔案的警告塞滿管道後 exiftool 會卡在寫入 stderr；
+// 程序結束時關閉 segments
+func readStderr(r *bufio.Reader, segments chan<- stderrSegment) {
+	defer close(segments)
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if marker := strings.TrimRight(line, "\r\n"); strings.HasPrefix(marker, "{ready") && strings.HasSuffix(marker, "}") {
+			segments <- stderrSegment{marker: marker, data: bytes.Clone(buf.Bytes())}
+			buf.Reset()
+		} else {
+			buf.WriteString(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// exiftoolOutput 單次執行的輸出
+type exiftoolOutput struct {
+	stdout []byte
+	stderr []byte
+}
+
+// execute 送出參數並以 -execute<seq> 序號標記讀回該次輸出
+func (p *exiftoolProcess) execute(seq uint64, args []string) (*exiftoolOutput, error) {
+	marker := fmt.Sprintf("{ready%d}", seq)
+
+	var buf bytes.Buffer
+	for _, arg := range args {
+		buf.WriteString(arg)
+		buf.WriteByte('\n')
+	}
+	// -echo4 在處理完成後把標記輸出到 stderr，讓 readStderr 能分段讀取
+	fmt.Fprintf(&buf, "-echo4\n%s\n-execute%d\n", marker, seq)
+
+	if _, err := p.stdin.Write(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("寫入 exiftool 失敗: %v", err)
+	}
+
+	stdout, err := readUntilMarker(p.stdout, marker)
+	if err != nil {
+		return nil, fmt.Errorf("讀取 exiftool 輸出失敗: %v", err)
+	}
+	segment, ok := <-p.stderr
+	if !ok {
+		return nil, fmt.Errorf("讀取 exiftool 錯誤輸出失敗: %v", io.ErrUnexpectedEOF)
+	}
+	if segment.marker != marker {
+		return nil, fmt.Errorf("exiftool 錯誤輸出的標記不符: 期望 %s，得到 %s", marker, segment.marker)
+	}
+
+	return &exiftoolOutput{stdout: stdout, stderr: segment.data}, nil
+}
+
+// stop 通知 exiftool 結束，逾時則強制終止
+func (p *exiftoolProcess) stop() {
+	io.WriteString(p.stdin, "-stay_open\nFalse\n")
+	p.stdin.Close()
+
+	exited := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		p.cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// kill 強制終止程序
+func (p *exiftoolProcess) kill() {
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+}
+
+// readUntilMarker 讀取直到遇到標記行為止，回傳標記之前的內容
+func readUntilMarker(r *bufio.Reader, marker string) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == marker {
+			return buf.Bytes(), nil
+		}
+		buf.WriteString(line)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseExifToolOutput 解析 exiftool -json 的輸出
+func parseExifToolOutput(output *exiftoolOutput) ([]ExifData, error) {
+	if len(bytes.TrimSpace(output.stdout)) == 0 {
+		if msg := strings.TrimSpace(string(output.stderr)); msg != "" {
+			return nil, fmt.Errorf("執行 exiftool 失敗: %s", msg)
+		}
+		return nil, fmt.Errorf("無法取得檔案資訊")
+	}
+
+	var data []ExifData
+	if err := json.Unmarshal(output.stdout, &data); err != nil {
+		return nil, fmt.Errorf("解析 exiftool 輸出失敗: %v", err)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("無法取得檔案資訊")
+	}
+
+	return data, nil
+}
@@ -0,0 +1,259 @@
+package exif
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExifTool 模擬 `exiftool -stay_open True -@ -` 的行為：
+// 每個存在的檔案輸出一筆 JSON，檔名含 crash 時直接結束程序，檔名含 noisy 時先在 stderr 輸出大量警告
+const fakeExifTool = `#!/bin/sh
+files=""
+marker=""
+while IFS= read -r line; do
+	case "$line" in
+	-stay_open)
+		read -r v
+		[ "$v" = "False" ] && exit 0
+		;;
+	-echo4)
+		read -r marker
+		;;
+	-execute*)
+		n="${line#-execute}"
+		out=""
+		for f in $files; do
+			case "$f" in
+			*crash*) exit 1 ;;
+			*noisy*)
+				i=0
+				while [ $i -lt 3000 ]; do
+					echo "Warning: [minor] Bad IFD0 directory entry $i - $f" >&2
+					i=$((i+1))
+				done
+				;;
+			esac
+			if [ -f "$f" ]; then
+				[ -n "$out" ] && out="$out,"
+				out="$out{\"SourceFile\":\"$f\",\"CreateDate\":\"2024:05:03 10:20:30\",\"Model\":\"FakeCam\"}"
+			else
+				echo "Error: File not found - $f" >&2
+			fi
+		done
+		[ -n "$out" ] && echo "[$out]"
+		echo "$marker" >&2
+		echo "{ready$n}"
+		files=""
+		;;
+	-*) ;;
+	*) files="$files $line" ;;
+	esac
+done
+`
+
+// installFakeExifTool 將假的 exiftool 放到 PATH 最前面
+func installFakeExifTool(t *testing.T) string {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "exiftool"), []byte(fakeExifTool), 0755); err != nil {
+		t.Fatalf("建立假的 exiftool 失敗: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return t.TempDir()
+}
+
+func writeTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("建立測試檔案失敗: %v", err)
+	}
+	return path
+}
+
+func TestExifToolGetExifData(t *testing.T) {
+	dataDir := installFakeExifTool(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	et, err := NewExifTool(ctx, 2)
+	if err != nil {
+		t.Fatalf("啟動 ExifTool 失敗: %v", err)
+	}
+	defer et.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		path := writeTestFile(t, dataDir, fmt.Sprintf("IMG_%04d.jpg", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := et.GetExifData(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if data.CreateDate != "2024:05:03 10:20:30" || data.Model != "FakeCam" {
+				errs <- fmt.Errorf("%s 的 EXIF 資料不符: %+v", path, data)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if _, err := et.GetExifData(filepath.Join(dataDir, "missing.jpg")); err == nil {
+		t.Error("不存在的檔案應回傳錯誤")
+	}
+}
+
+func TestExifToolRestartAfterCrash(t *testing.T) {
+	dataDir := installFakeExifTool(t)
+	et, err := NewExifTool(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("啟動 ExifTool 失敗: %v", err)
+	}
+	defer et.Close()
+
+	if _, err := et.GetExifData(writeTestFile(t, dataDir, "crash.jpg")); err == nil {
+		t.Fatal("exiftool 崩潰時應回傳錯誤")
+	}
+
+	data, err := et.GetExifData(writeTestFile(t, dataDir, "IMG_0001.jpg"))
+	if err != nil {
+		t.Fatalf("重新啟動後應可正常處理: %v", err)
+	}
+	if data.Model != "FakeCam" {
+		t.Errorf("Model 不符: %s", data.Model)
+	}
+}
+
+func TestExifToolCloseOnContextCancel(t *testing.T) {
+	dataDir := installFakeExifTool(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	et, err := NewExifTool(ctx, 2)
+	if err != nil {
+		t.Fatalf("啟動 ExifTool 失敗: %v", err)
+	}
+	cancel()
+	<-et.done
+
+	_, err = et.GetExifData(writeTestFile(t, dataDir, "IMG_0001.jpg"))
+	if !errors.Is(err, ErrExifToolClosed) {
+		t.Errorf("取消後應回傳 ErrExifToolClosed，得到: %v", err)
+	}
+}
+
+func TestExifToolGetExifDataBatch(t *testing.T) {
+	dataDir := installFakeExifTool(t)
+	et, err := NewExifTool(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("啟動 ExifTool 失敗: %v", err)
+	}
+	defer et.Close()
+
+	tests := []struct {
+		name    string
+		files   []string
+		missing []string
+	}{
+		{
+			name:  "全部成功",
+			files: []string{"IMG_0001.jpg", "IMG_0002.jpg", "IMG_0003.jpg"},
+		},
+		{
+			name:    "部分檔案不存在",
+			files:   []string{"IMG_0004.jpg", "IMG_0005.jpg"},
+			missing: []string{"gone.jpg"},
+		},
+		{
+			name:    "批次中有檔案讓 exiftool 崩潰",
+			files:   []string{"IMG_0006.jpg", "IMG_0007.jpg"},
+			missing: []string{"crash.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, name := range tt.files {
+				paths = append(paths, writeTestFile(t, dataDir, name))
+			}
+			var failed []string
+			for _, name := range tt.missing {
+				path := filepath.Join(dataDir, name)
+				if name == "crash.jpg" {
+					path = writeTestFile(t, dataDir, name)
+				}
+				failed = append(failed, path)
+				paths = append(paths, path)
+			}
+
+			result, err := et.GetExifDataBatch(paths)
+			if err != nil {
+				t.Fatalf("批次查詢失敗: %v", err)
+			}
+			for _, path := range paths[:len(tt.files)] {
+				if data, ok := result[path]; !ok || data.Model != "FakeCam" {
+					t.Errorf("%s 應取得 EXIF 資料，得到: %+v", path, data)
+				}
+			}
+			for _, path := range failed {
+				if _, ok := result[path]; ok {
+					t.Errorf("%s 應歸類為失敗", path)
+				}
+			}
+		})
+	}
+}
+
+func TestExifToolNoisyStderr(t *testing.T) {
+	dataDir := installFakeExifTool(t)
+	et, err := NewExifTool(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("啟動 ExifTool 失敗: %v", err)
+	}
+	defer et.Close()
+
+	// 警告量超過管道緩衝區時，stderr 與 stdout 必須同時讀取才不會卡住
+	paths := []string{writeTestFile(t, dataDir, "noisy_0001.jpg"), writeTestFile(t, dataDir, "noisy_0002.jpg")}
+	type batchResult struct {
+		datas map[string]*ExifData
+		err   error
+	}
+	done := make(chan batchResult, 1)
+	go func() {
+		datas, err := et.GetExifDataBatch(paths)
+		done <- batchResult{datas, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("批次取得失敗: %v", r.err)
+		}
+		if len(r.datas) != len(paths) {
+			t.Errorf("取得 %d 個檔案，want %d", len(r.datas), len(paths))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("大量警告時 exiftool 卡住")
+	}
+
+	// 之後的查詢仍使用同一個程序，錯誤輸出的分段不可錯位
+	if _, err := et.GetExifData(filepath.Join(dataDir, "missing.jpg")); err == nil {
+		t.Error("不存在的檔案應回傳錯誤")
+	}
+	if data, err := et.GetExifData(paths[0]); err != nil || data.Model != "FakeCam" {
+		t.Errorf("後續查詢失敗: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package exif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+// ExtractorType 中繼資料擷取器類型
+type ExtractorType string
+
+const (
+	// ExifToolExtractorType 使用 exiftool 常駐程序
+	ExifToolExtractorType ExtractorType = "exiftool"
+	// NativeExtractorType 使用內建的原生解析器
+	NativeExtractorType ExtractorType = "native"
+	// XMPExtractorType 讀取檔案旁的 XMP sidecar
+	XMPExtractorType ExtractorType = "xmp"
+	// SidecarExtractorType 讀取檔案旁的 JSON sidecar
+	SidecarExtractorType ExtractorType = "sidecar"
+	// FilenameExtractorType 從檔名推測拍攝時間
+	FilenameExtractorType ExtractorType = "filename"
+	// ModTimeExtractorType 使用檔案系統的修改時間
+	ModTimeExtractorType ExtractorType = "mtime"
+)
+
+// 中繼資料欄位，合併與記錄來源時以欄位為單位
+const (
+	FieldDate  = "date"
+	FieldMake  = "make"
+	FieldModel = "model"
+	FieldGPS   = "gps"
+	FieldLens  = "lens"
+	// FieldContentID Live Photo 的 ContentIdentifier
+	FieldContentID = "content_id"
+	// FieldBurst iPhone 連拍的 BurstUUID
+	FieldBurst = "burst"
+
+	FieldRating     = "rating"
+	FieldKeywords   = "keywords"
+	FieldDimensions = "dimensions"
+	FieldSequence   = "sequence"
+)
+
+// Extractor 中繼資料擷取器，回傳的 map 只包含有取得資料的檔案
+type Extractor interface {
+	Name() string
+	Extract(paths []string) (map[string]*ExifData, error)
+}
+
+// NewExtractor 依類型建立擷取器，exifTool 為 nil 時 exiftool 擷取器不會回傳任何資料，
+// dirs 為尋找 sidecar 時使用的資料夾快取，nil 表示每次都讀取資料夾
+func NewExtractor(extractorType ExtractorType, cfg *config.Config, exifTool *ExifTool, dirs *DirCache) (Extractor, error) {
+	switch extractorType {
+	case ExifToolExtractorType:
+		return &ExifToolExtractor{tool: exifTool}, nil
+	case NativeExtractorType:
+		return &NativeExtractor{}, nil
+	case XMPExtractorType:
+		return &XMPExtractor{dirs: dirs}, nil
+	case SidecarExtractorType:
+		return &SidecarExtractor{dirs: dirs}, nil
+	case FilenameExtractorType:
+		return NewFilenameExtractor(cfg.FilenamePatterns)
+	case ModTimeExtractorType:
+		return &ModTimeExtractor{}, nil
+	default:
+		return nil, fmt.Errorf("不支援的中繼資料擷取器: %s", extractorType)
+	}
+}
+
+// ExtractorChain 依序呼叫多個擷取器，後面的擷取器只補齊前面留空的欄位
+type ExtractorChain struct {
+	extractors []Extractor
+}
+
+// NewExtractorChain 依設定的 metadata_extractors 順序建立擷取器串鏈
+func NewExtractorChain(cfg *config.Config, exifTool *ExifTool, dirs *DirCache) (*ExtractorChain, error) {
+	if len(cfg.MetadataExtractors) == 0 {
+		return nil, errors.New("至少需要一個中繼資料擷取器")
+	}
+
+	chain := &ExtractorChain{}
+	for _, t := range cfg.MetadataExtractors {
+		extractor, err := NewExtractor(ExtractorType(t), cfg, exifTool, dirs)
+		if err != nil {
+			return nil, err
+		}
+		chain.extractors = append(chain.extractors, extractor)
+	}
+	return chain, nil
+}
+
+// Name 擷取器名稱
+func (c *ExtractorChain) Name() string {
+	names := make([]string, len(c.extractors))
+	for i, extractor := range c.extractors {
+		names[i] = extractor.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Extract 依序對所有檔案呼叫擷取器，每個欄位只採用第一個提供的擷取器，
+// 讓後面的 sidecar 也能補上 GPS、鏡頭等欄位；exiftool 成本較高，只處理日期或裝置仍缺少的檔案
+func (c *ExtractorChain) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	var errs []error
+
+	for _, extractor := range c.extractors {
+		pending := paths
+		if _, ok := extractor.(*ExifToolExtractor); ok {
+			pending = nil
+			for _, path := range paths {
+				if data := result[path]; data == nil || !data.isComplete() {
+					pending = append(pending, path)
+				}
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		datas, err := extractor.Extract(pending)
+		if err != nil {
+			if errors.Is(err, ErrExifToolClosed) {
+				return result, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %v", extractor.Name(), err))
+		}
+
+		for path, data := range datas {
+			if result[path] == nil {
+				result[path] = &ExifData{SourceFile: path}
+			}
+			result[path].merge(data, extractor.Name())
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// fieldGroups 每個欄位對應的 ExifData 屬性，同一欄位的屬性一起合併，
+// 避免後面的擷取器補上優先順序較高的日期屬性，或是把別的來源的時區與 GPS 時間套到這個日期上
+func (e *ExifData) fieldGroups() []struct {
+	name   string
+	values []*string
+} {
+	return []struct {
+		name   string
+		values []*string
+	}{
+		{FieldDate, []*string{
+			&e.DateTimeOriginal, &e.OffsetTimeOriginal, &e.SubSecTimeOriginal,
+			&e.CreationDate, &e.CreateDate, &e.MediaCreateDate,
+			&e.GPSDateStamp, &e.GPSTimeStamp, &e.DatePattern,
+		}},
+		{FieldMake, []*string{&e.Make}},
+		{FieldModel, []*string{&e.Model, &e.SerialNumber}},
+		{FieldGPS, []*string{&e.GPSLatitude, &e.GPSLongitude}},
+		{FieldLens, []*string{&e.LensModel}},
+		{FieldContentID, []*string{&e.ContentIdentifier}},
+		{FieldBurst, []*string{&e.BurstUUID}},
+	}
+}
+
+// fill 將 src 中 e 尚未有值的欄位填入 e，回傳有填入的欄位
+func (e *ExifData) fill(src *ExifData) []string {
+	var filled []string
+	dstGroups, srcGroups := e.fieldGroups(), src.fieldGroups()
+	for i, group := range dstGroups {
+		if !isEmptyGroup(group.values) || isEmptyGroup(srcGroups[i].values) {
+			continue
+		}
+		for j, value := range group.values {
+			*value = *srcGroups[i].values[j]
+		}
+		filled = append(filled, group.name)
+	}
+
+	if e.Rating == 0 && src.Rating != 0 {
+		e.Rating = src.Rating
+		filled = append(filled, FieldRating)
+	}
+	if e.ImageWidth == 0 && e.ImageHeight == 0 && src.ImageWidth > 0 && src.ImageHeight > 0 {
+		e.ImageWidth, e.ImageHeight = src.ImageWidth, src.ImageHeight
+		filled = append(filled, FieldDimensions)
+	}
+	if e.SequenceNumber == 0 && src.SequenceNumber > 0 {
+		e.SequenceNumber = src.SequenceNumber
+		filled = append(filled, FieldSequence)
+	}
+	if len(e.Keywords) == 0 && len(src.Keywords) > 0 {
+		e.Keywords = src.Keywords
+		filled = append(filled, FieldKeywords)
+	}
+	return filled
+}
+
+// merge 將 src 中 e 尚未有值的欄位填入 e，並記錄欄位來源
+func (e *ExifData) merge(src *ExifData, source string) {
+	for _, field := range e.fill(src) {
+		if e.Sources == nil {
+			e.Sources = make(map[string]string)
+		}
+		e.Sources[field] = source
+	}
+}
+
+// isComplete 是否已有拍攝日期與裝置
+func (e *ExifData) isComplete() bool {
+	return e.captureDate() != "" && e.Model != ""
+}
+
+func isEmptyGroup(values []*string) bool {
+	for _, value := range values {
+		if *value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ExifToolExtractor 透過 exiftool 常駐程序擷取
+type ExifToolExtractor struct {
+	tool *ExifTool
+}
+
+// Name 擷取器名稱
+func (x *ExifToolExtractor) Name() string {
+	return string(ExifToolExtractorType)
+}
+
+// Extract 整批交給 exiftool
+func (x *ExifToolExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	if x.tool == nil {
+		return nil, nil
+	}
+	return x.tool.GetExifDataBatch(paths)
+}
+
+// NativeExtractor 使用內建的原生解析器擷取
+type NativeExtractor struct{}
+
+// Name 擷取器名稱
+func (x *NativeExtractor) Name() string {
+	return string(NativeExtractorType)
+}
+
+// Extract 逐一以原生解析器讀取，不支援的檔案略過
+func (x *NativeExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	for _, path := range paths {
+		if data, err := ReadNative(path); err == nil {
+			result[path] = data
+		}
+	}
+	return result, nil
+}
+
+// SidecarExtractor 讀取 JSON sidecar，支援 Google Takeout 與 exiftool -json 兩種格式
+type SidecarExtractor struct {
+	dirs *DirCache
+}
+
+// Name 擷取器名稱
+func (x *SidecarExtractor) Name() string {
+	return string(SidecarExtractorType)
+}
+
+// Extract 讀取每個檔案的 JSON sidecar
+func (x *SidecarExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	var errs []error
+	for _, path := range paths {
+		sidecar := findJSONSidecar(path, x.dirs)
+		if sidecar == "" {
+			continue
+		}
+		content, err := os.ReadFile(sidecar)
+		if err != nil {
+			continue
+		}
+
+		data, err := parseJSONSidecar(path, content)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", sidecar, err))
+			continue
+		}
+		if data.hasMetadata() {
+			result[path] = data
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// parseJSONSidecar 解析 Google Takeout 或 exiftool -json 格式的 sidecar
+func parseJSONSidecar(path string, content []byte) (*ExifData, error) {
+	if takeout, err := parseTakeout(content); err == nil {
+		return takeout.exifData(path)
+	}
+
+	var data ExifData
+	var list []ExifData
+	if err := json.Unmarshal(content, &list); err == nil && len(list) > 0 {
+		data = list[0]
+	} else if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("解析 sidecar 失敗: %v", err)
+	}
+	data.SourceFile = path
+	return &data, nil
+}
+
+// ModTimeExtractor 使用檔案系統的修改時間作為最後的日期來源
+type ModTimeExtractor struct{}
+
+// Name 擷取器名稱
+func (x *ModTimeExtractor) Name() string {
+	return string(ModTimeExtractorType)
+}
+
+// Extract 讀取檔案修改時間
+func (x *ModTimeExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		result[path] = &ExifData{SourceFile: path, CreateDate: info.ModTime().Format(exifTimeLayout)}
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+package exif
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestExtractorChain(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	nef := write("DSC_0001.NEF", sampleTIFF(binary.LittleEndian))
+	sidecar := write("IMG_0001.png", png)
+	write("IMG_0001.png.json", []byte(`[{"SourceFile":"IMG_0001.png","CreateDate":"2022:01:02 03:04:05","Model":"Pixel 7"}]`))
+	named := write("Screenshot_20230514-101112.png", png)
+	video := write("MVI_0001.mov", mkbox("moov", mvhdBox("mvhd", time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC))))
+	plain := write("scan.png", png)
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)
+	if err := os.Chtimes(plain, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := NewExtractorChain(&config.Config{MetadataExtractors: []string{"native", "exiftool", "sidecar", "filename", "mtime"}}, nil, nil)
+	if err != nil {
+		t.Fatalf("建立擷取器串鏈失敗: %v", err)
+	}
+	result, err := chain.Extract([]string{nef, sidecar, named, video, plain})
+	if err != nil {
+		t.Fatalf("擷取失敗: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		date    string
+		model   string
+		sources map[string]string
+	}{
+		{nef, "2024:05:03 10:20:30", "iPhone 11", map[string]string{FieldDate: "native", FieldMake: "native", FieldModel: "native", FieldGPS: "native"}},
+		{sidecar, "2022:01:02 03:04:05", "Pixel 7", map[string]string{FieldDate: "sidecar", FieldModel: "sidecar"}},
+		{named, "2023:05:14 10:11:12", "", map[string]string{FieldDate: "filename"}},
+		{video, "2021:06:07 08:09:10", "", map[string]string{FieldDate: "native"}},
+		{plain, "2020:01:02 03:04:05", "", map[string]string{FieldDate: "mtime"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			data := result[tt.path]
+			if data == nil {
+				t.Fatal("應取得中繼資料")
+			}
+			if date := data.captureDate(); date != tt.date || data.Model != tt.model {
+				t.Errorf("資料不符: date=%s model=%s", data.captureDate(), data.Model)
+			}
+			if len(data.Sources) != len(tt.sources) {
+				t.Errorf("來源不符: %v", data.Sources)
+			}
+			for field, source := range tt.sources {
+				if data.Sources[field] != source {
+					t.Errorf("%s 的來源應為 %s，得到 %s", field, source, data.Sources[field])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractorChainFillsFromLaterExtractors(t *testing.T) {
+	dir := t.TempDir()
+	// 原生解析器可以取得日期與裝置，但沒有 GPS 與鏡頭
+	nef := filepath.Join(dir, "DSC_0002.NEF")
+	tiff := buildTIFF(binary.LittleEndian,
+		[]testEntry{{tagModel, typeASCII, "NIKON Z 6"}},
+		[]testEntry{{tagDateTimeOriginal, typeASCII, "2024:05:03 10:20:30"}},
+		nil,
+	)
+	if err := os.WriteFile(nef, tiff, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sidecar := `[{"SourceFile":"DSC_0002.NEF","DateTimeOriginal":"2000:01:01 00:00:00","Model":"Other",` +
+		`"GPSLatitude":"25 deg 2' 0.00\" N","GPSLongitude":"121 deg 33' 0.00\" E","LensModel":"NIKKOR Z 24-70mm"}]`
+	if err := os.WriteFile(nef+".json", []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := NewExtractorChain(&config.Config{MetadataExtractors: []string{"native", "exiftool", "sidecar"}}, nil, nil)
+	if err != nil {
+		t.Fatalf("建立擷取器串鏈失敗: %v", err)
+	}
+	result, err := chain.Extract([]string{nef})
+	if err != nil {
+		t.Fatalf("擷取失敗: %v", err)
+	}
+
+	data := result[nef]
+	if data == nil {
+		t.Fatal("應取得中繼資料")
+	}
+	// 原生解析器已提供的欄位不被 sidecar 覆蓋
+	if data.captureDate() != "2024:05:03 10:20:30" || data.Model != "NIKON Z 6" {
+		t.Errorf("資料不符: date=%s model=%s", data.captureDate(), data.Model)
+	}
+	if data.GPSLatitude == "" || data.GPSLongitude == "" || data.LensModel != "NIKKOR Z 24-70mm" {
+		t.Errorf("應由 sidecar 補上 GPS 與鏡頭: %s %s %s", data.GPSLatitude, data.GPSLongitude, data.LensModel)
+	}
+	if data.Sources[FieldGPS] != "sidecar" || data.Sources[FieldDate] != "native" {
+		t.Errorf("來源不符: %v", data.Sources)
+	}
+}
+
+func TestNewExtractorChainInvalid(t *testing.T) {
+	if _, err := NewExtractorChain(&config.Config{MetadataExtractors: []string{"native", "unknown"}}, nil, nil); err == nil {
+		t.Error("不支援的擷取器應回傳錯誤")
+	}
+	if _, err := NewExtractorChain(&config.Config{}, nil, nil); err == nil {
+		t.Error("沒有擷取器應回傳錯誤")
+	}
+}
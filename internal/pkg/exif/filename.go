@@ -0,0 +1,118 @@
+package exif
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// DefaultFilenamePatterns 內建的檔名日期規則，設定檔的 filename_patterns 會先於這些規則比對
+var DefaultFilenamePatterns = []string{
+	// PXL_20240101_123456789.jpg、VID_20190203_101010.mp4、Screenshot_20230514-101112.png
+	`(?P<year>(?:19|20)\d{2})(?P<month>\d{2})(?P<day>\d{2})[_-](?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`,
+	// Screenshot 2023-05-14 at 10.11.12.png、Screenshot_2023-05-14-10-11-12.png
+	`(?P<year>(?:19|20)\d{2})-(?P<month>\d{2})-(?P<day>\d{2})(?:[ _]at[ _]|[ _-])(?P<hour>\d{2})[.-](?P<minute>\d{2})[.-](?P<second>\d{2})`,
+	// IMG-20230514-WA0003.jpg
+	`(?P<year>(?:19|20)\d{2})(?P<month>\d{2})(?P<day>\d{2})-WA\d+`,
+	// 2023-05-14.jpg、photo_2023-05-14.jpg
+	`(?P<year>(?:19|20)\d{2})-(?P<month>\d{2})-(?P<day>\d{2})`,
+}
+
+// ErrNoFilenameDate 檔名不符合任何日期規則
+var ErrNoFilenameDate = errors.New("檔名不符合任何日期規則")
+
+// filenamePatterns 已編譯的檔名規則，避免每個檔案重新編譯
+var filenamePatterns sync.Map
+
+// compileFilenamePattern 編譯並快取檔名規則
+func compileFilenamePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := filenamePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("無效的檔名規則 %q: %v", pattern, err)
+	}
+	filenamePatterns.Store(pattern, re)
+	return re, nil
+}
+
+// MatchFilenameDate 依序比對設定的規則與內建規則，回傳推測的時間與符合的規則
+func MatchFilenameDate(name string, patterns []string) (time.Time, string, error) {
+	for _, pattern := range append(append([]string{}, patterns...), DefaultFilenamePatterns...) {
+		re, err := compileFilenamePattern(pattern)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		parts := map[string]string{"month": "01", "day": "01", "hour": "00", "minute": "00", "second": "00"}
+		for i, group := range re.SubexpNames() {
+			if group != "" && match[i] != "" {
+				parts[group] = match[i]
+			}
+		}
+		if parts["year"] == "" {
+			continue
+		}
+
+		date := fmt.Sprintf("%s:%s:%s %s:%s:%s", parts["year"], parts["month"], parts["day"], parts["hour"], parts["minute"], parts["second"])
+		if t, err := parseExifTime(date); err == nil {
+			return t, pattern, nil
+		}
+	}
+	return time.Time{}, "", ErrNoFilenameDate
+}
+
+// applyFilenameDate 從檔名推測日期並寫入 DateTimeOriginal，同時記錄符合的規則與來源
+func (e *ExifData) applyFilenameDate(path string, patterns []string) (time.Time, error) {
+	t, pattern, err := MatchFilenameDate(filepath.Base(path), patterns)
+	if err != nil {
+		return t, err
+	}
+	e.DateTimeOriginal = t.Format(exifTimeLayout)
+	e.DatePattern = pattern
+	if e.Sources == nil {
+		e.Sources = make(map[string]string)
+	}
+	e.Sources[FieldDate] = string(FilenameExtractorType)
+	return t, nil
+}
+
+// FilenameExtractor 依檔名規則推測拍攝時間，規則使用 year、month、day、hour、minute、second 具名群組
+type FilenameExtractor struct {
+	patterns []string
+}
+
+// NewFilenameExtractor 建立檔名擷取器，patterns 會先於內建規則比對
+func NewFilenameExtractor(patterns []string) (*FilenameExtractor, error) {
+	for _, pattern := range patterns {
+		if _, err := compileFilenamePattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return &FilenameExtractor{patterns: patterns}, nil
+}
+
+// Name 擷取器名稱
+func (x *FilenameExtractor) Name() string {
+	return string(FilenameExtractorType)
+}
+
+// Extract 依序比對檔名規則
+func (x *FilenameExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	for _, path := range paths {
+		data := &ExifData{SourceFile: path}
+		if _, err := data.applyFilenameDate(path, x.patterns); err == nil {
+			result[path] = data
+		}
+	}
+	return result, nil
+}
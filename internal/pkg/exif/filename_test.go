@@ -0,0 +1,62 @@
+package exif
+
+import (
+	"path/filepath"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestMatchFilenameDate(t *testing.T) {
+	custom := []string{`^DJI_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})`}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected string
+	}{
+		{"PXL_20240101_123456789.jpg", nil, "2024:01:01 12:34:56"},
+		{"VID_20190203_101010.mp4", nil, "2019:02:03 10:10:10"},
+		{"IMG-20230514-WA0003.jpg", nil, "2023:05:14 00:00:00"},
+		{"Screenshot_20230514-101112.png", nil, "2023:05:14 10:11:12"},
+		{"Screenshot 2023-05-14 at 10.11.12.png", nil, "2023:05:14 10:11:12"},
+		{"photo_2023-05-14.jpg", nil, "2023:05:14 00:00:00"},
+		{"DJI_20220304050607_0001.jpg", custom, "2022:03:04 05:06:07"},
+		{"IMG_20231345_101010.jpg", nil, ""},
+		{"IMG_1234.jpg", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, pattern, err := MatchFilenameDate(tt.name, tt.patterns)
+			if tt.expected == "" {
+				if err == nil {
+					t.Errorf("不應符合任何規則，得到 %s (%s)", date, pattern)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("應符合規則: %v", err)
+			}
+			if got := date.Format(exifTimeLayout); got != tt.expected {
+				t.Errorf("日期不符: 期望 %s，得到 %s (%s)", tt.expected, got, pattern)
+			}
+		})
+	}
+}
+
+func TestGetTargetPathFilenameFallback(t *testing.T) {
+	cfg := &config.Config{DstDir: t.TempDir(), DateFormat: "2006-01"}
+	data := &ExifData{}
+
+	target, err := GetTargetPath("/src/IMG-20230514-WA0003.jpg", data, cfg, nil)
+	if err != nil {
+		t.Fatalf("取得目標路徑失敗: %v", err)
+	}
+	if expected := filepath.Join(cfg.DstDir, "2023-05", "unknown_device", "IMG-20230514-WA0003.jpg"); target != expected {
+		t.Errorf("目標路徑不符: 期望 %s，得到 %s", expected, target)
+	}
+	if data.DatePattern == "" || data.Sources[FieldDate] != "filename" {
+		t.Errorf("應記錄符合的規則與來源: %q %v", data.DatePattern, data.Sources)
+	}
+}
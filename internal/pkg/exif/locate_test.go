@@ -0,0 +1,57 @@
+package exif
+
+import (
+	"path/filepath"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+// countingGeocoder 記錄查詢次數，一律回傳台北
+type countingGeocoder struct {
+	calls int
+}
+
+func (g *countingGeocoder) GetLocationFromGPS(lat, lon float64) (*geocoding.CountryCity, error) {
+	g.calls++
+	return &geocoding.CountryCity{Country: "TWN", City: "New Taipei", Place: "Office"}, nil
+}
+
+func TestLocate(t *testing.T) {
+	srcDir := t.TempDir()
+	path := filepath.Join(srcDir, "IMG_0001.JPG")
+	cfg := &config.Config{SrcDir: srcDir, DstDir: t.TempDir(), DateFormat: "2006-01", EnableGeoTag: true,
+		PathTemplate: "{{.Date}}-{{.Country}}-{{.City}}-{{.Place}}/{{.Name}}{{.Ext}}"}
+	data := &ExifData{SourceFile: path, DateTimeOriginal: "2024:05:03 10:20:30", Model: "FakeCam",
+		GPSLatitude: "25 deg 2' 0.00\" N", GPSLongitude: "121 deg 33' 0.00\" E"}
+
+	geocoder := &countingGeocoder{}
+	for i := 0; i < 2; i++ {
+		location, err := data.Locate(geocoder)
+		if err != nil {
+			t.Fatalf("查詢地點失敗: %v", err)
+		}
+		if location == nil || location.Country != "TWN" {
+			t.Fatalf("地點不符: %+v", location)
+		}
+	}
+	if geocoder.calls != 1 {
+		t.Errorf("查詢次數 = %d, want 1", geocoder.calls)
+	}
+
+	target, err := GetTargetPath(path, data, cfg, nil)
+	if err != nil {
+		t.Fatalf("取得目標路徑失敗: %v", err)
+	}
+	expected := filepath.Join(cfg.DstDir, "2024-05-TWN-New_Taipei-Office", "IMG_0001.JPG")
+	if target != expected {
+		t.Errorf("目標路徑 = %s, want %s", target, expected)
+	}
+
+	// 無法解析的 GPS 回傳錯誤
+	bad := &ExifData{GPSLatitude: "north", GPSLongitude: "121 deg 33' 0.00\" E"}
+	if _, err := bad.Locate(geocoder); err == nil {
+		t.Error("無法解析的緯度應回傳錯誤")
+	}
+}
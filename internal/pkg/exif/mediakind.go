@@ -0,0 +1,160 @@
+package exif
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 媒體類型
+const (
+	KindPhoto = "photo"
+	KindVideo = "video"
+	KindRAW   = "raw"
+)
+
+// videoExts 影片副檔名
+var videoExts = map[string]bool{
+	".mp4": true, ".mov": true, ".m4v": true, ".3gp": true, ".3g2": true, ".qt": true,
+	".mkv": true, ".avi": true, ".wmv": true, ".flv": true, ".mpeg": true, ".mpg": true,
+	".hevc": true, ".mts": true, ".m2ts": true, ".webm": true,
+}
+
+// rawExts 相機 RAW 副檔名
+var rawExts = map[string]bool{
+	".cr2": true, ".cr3": true, ".crw": true, ".nef": true, ".nrw": true, ".arw": true,
+	".srf": true, ".sr2": true, ".raf": true, ".rw2": true, ".orf": true, ".pef": true,
+	".dng": true, ".rwl": true, ".raw": true, ".srw": true, ".x3f": true, ".3fr": true,
+	".iiq": true, ".erf": true, ".kdc": true, ".mrw": true,
+}
+
+// MediaKind 依副檔名判斷媒體類型：photo、video 或 raw
+func MediaKind(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case videoExts[ext]:
+		return KindVideo
+	case rawExts[ext]:
+		return KindRAW
+	default:
+		return KindPhoto
+	}
+}
+
+// SniffMIME 依檔案開頭的內容判斷 MIME 類型，補上 http.DetectContentType 無法辨識的 HEIF、QuickTime 與 TIFF 架構的格式
+func SniffMIME(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return sniffMIME(head[:n]), nil
+}
+
+// sniffMIME 依內容開頭判斷 MIME 類型
+func sniffMIME(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		brand := string(head[8:12])
+		switch {
+		case brand == "avif" || brand == "avis":
+			return "image/avif"
+		case heifBrands[brand]:
+			return "image/heic"
+		case brand == "crx ":
+			return "image/x-canon-cr3"
+		case brand == "qt  ":
+			return "video/quicktime"
+		case strings.HasPrefix(brand, "3g"):
+			return "video/3gpp"
+		default:
+			return "video/mp4"
+		}
+	}
+	if bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+
+	mimeType := http.DetectContentType(head)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return mimeType
+}
+
+// mediaInfo 提供版面規則比對需要的檔案資訊，MIME 類型與尺寸在第一次使用時才讀取檔案
+type mediaInfo struct {
+	path   string
+	dir    string
+	data   *ExifData
+	mime   string
+	width  int
+	height int
+	loaded bool
+}
+
+// Ext 小寫的副檔名
+func (m *mediaInfo) Ext() string {
+	return strings.ToLower(filepath.Ext(m.path))
+}
+
+// Kind 媒體類型
+func (m *mediaInfo) Kind() string {
+	return MediaKind(m.path)
+}
+
+// MIMEType 依檔案內容判斷的 MIME 類型，無法讀取時為空字串
+func (m *mediaInfo) MIMEType() string {
+	if m.mime == "" {
+		m.mime, _ = SniffMIME(m.path)
+	}
+	return m.mime
+}
+
+// Dimensions 影像尺寸，中繼資料沒有時讀取 JPEG、PNG、GIF 的標頭
+func (m *mediaInfo) Dimensions() (width, height int) {
+	if m.data.ImageWidth > 0 && m.data.ImageHeight > 0 {
+		return m.data.ImageWidth, m.data.ImageHeight
+	}
+	if !m.loaded {
+		m.loaded = true
+		if f, err := os.Open(m.path); err == nil {
+			if config, _, err := image.DecodeConfig(f); err == nil {
+				m.width, m.height = config.Width, config.Height
+			}
+			f.Close()
+		}
+	}
+	return m.width, m.height
+}
+
+// Metadata 取得中繼資料欄位的值
+func (m *mediaInfo) Metadata(field string) string {
+	switch field {
+	case "make":
+		return m.data.Make
+	case "model":
+		return m.data.Model
+	case "lens":
+		return m.data.LensModel
+	case "serial_number":
+		return m.data.SerialNumber
+	case "name":
+		return filepath.Base(m.path)
+	case "dir":
+		return m.dir
+	}
+	return ""
+}
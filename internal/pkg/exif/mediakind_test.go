@@ -0,0 +1,105 @@
+package exif
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestSniffMIME(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     []byte
+		expected string
+	}{
+		{"JPEG", []byte("\xff\xd8\xff\xe1\x00\x10Exif"), "image/jpeg"},
+		{"HEIC", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), "image/heic"},
+		{"AVIF", []byte("\x00\x00\x00\x18ftypavif\x00\x00\x00\x00"), "image/avif"},
+		{"MOV", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime"},
+		{"MP4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), "video/mp4"},
+		{"CR3", []byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01"), "image/x-canon-cr3"},
+		{"TIFF", []byte("II*\x00\x08\x00\x00\x00"), "image/tiff"},
+		{"文字", []byte("hello"), "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffMIME(tt.head); got != tt.expected {
+				t.Errorf("MIME 類型不符: 期望 %s，得到 %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetTargetPathLayoutRules(t *testing.T) {
+	srcDir := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1179, 2556))); err != nil {
+		t.Fatal(err)
+	}
+	screenshot := filepath.Join(srcDir, "IMG_0002.PNG")
+	if err := os.WriteFile(screenshot, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		SrcDir:     srcDir,
+		DstDir:     t.TempDir(),
+		DateFormat: "2006-01",
+		LayoutRules: []config.LayoutRule{
+			{Name: "videos", Kinds: []string{"video"}, PathTemplate: "Videos/{{.Year}}/{{.Name}}{{.Ext}}"},
+			{
+				Name:         "screenshots",
+				MIMETypes:    []string{"image/png"},
+				MinHeight:    2000,
+				Metadata:     map[string]string{"model": "^$"},
+				PathTemplate: "Screenshots/{{.Date}}/{{.Name}}{{.Ext}}",
+				DateFormat:   "2006",
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		data     ExifData
+		expected string
+	}{
+		{
+			name:     "影片",
+			path:     filepath.Join(srcDir, "VID_0001.MOV"),
+			data:     ExifData{CreationDate: "2024:05:03 10:20:30+08:00", Model: "iPhone 15"},
+			expected: "Videos/2024/VID_0001.MOV",
+		},
+		{
+			name:     "截圖使用自己的日期格式",
+			path:     screenshot,
+			data:     ExifData{DateTimeOriginal: "2024:05:03 10:20:30"},
+			expected: "Screenshots/2024/IMG_0002.PNG",
+		},
+		{
+			name:     "沒有符合的規則使用預設目錄結構",
+			path:     filepath.Join(srcDir, "IMG_0001.JPG"),
+			data:     ExifData{DateTimeOriginal: "2024:05:03 10:20:30", Model: "iPhone 15"},
+			expected: "2024-05/iPhone_15/IMG_0001.JPG",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.data.SourceFile = tt.path
+			target, err := GetTargetPath(tt.path, &tt.data, cfg, nil)
+			if err != nil {
+				t.Fatalf("取得目標路徑失敗: %v", err)
+			}
+			if expected := filepath.Join(cfg.DstDir, filepath.FromSlash(tt.expected)); target != expected {
+				t.Errorf("目標路徑不符: 期望 %s，得到 %s", expected, target)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	// ErrUnsupportedNative 原生解析器不支援此檔案格式
+	ErrUnsupportedNative = errors.New("原生解析器不支援此檔案格式")
+	// ErrNoExif 檔案中沒有可用的 EXIF 資料
+	ErrNoExif = errors.New("找不到 EXIF 資料")
+)
+
+// exifHeader JPEG APP1 區段中 EXIF 資料的開頭
+var exifHeader = []byte("Exif\x00\x00")
+
+// qtTopLevelBoxes ISO-BMFF 檔案開頭可能出現的 box，舊的 MOV 不一定以 ftyp 開頭
+var qtTopLevelBoxes = map[string]bool{
+	"ftyp": true, "moov": true, "mdat": true, "wide": true, "free": true, "skip": true,
+}
+
+// ReadNative 不依賴 exiftool，直接解析 JPEG、TIFF 架構的 RAW（DNG、NEF、ARW、CR2），
+// ISO-BMFF 架構的 HEIC/HEIF 與 CR3，以及 MP4/MOV 影片
+func ReadNative(path string) (*ExifData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeNative(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	data.SourceFile = path
+	return data, nil
+}
+
+// decodeNative 依檔頭判斷格式並解析
+func decodeNative(r io.ReaderAt, size int64) (*ExifData, error) {
+	magic := make([]byte, 8)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return nil, ErrUnsupportedNative
+	}
+
+	var data *ExifData
+	var err error
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		data, err = decodeJPEG(r, size)
+	case bytes.Equal(magic[:4], []byte("II*\x00")) || bytes.Equal(magic[:4], []byte("MM\x00*")):
+		data, err = decodeTIFF(r, size)
+	case qtTopLevelBoxes[string(magic[4:8])]:
+		data, err = decodeBMFF(r, size)
+	default:
+		return nil, ErrUnsupportedNative
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !data.hasMetadata() {
+		return nil, ErrNoExif
+	}
+	return data, nil
+}
+
+// decodeJPEG 找出 APP1 的 EXIF 與 XMP 區段，EXIF 沒有的欄位再由內嵌的 XMP 補齊
+func decodeJPEG(r io.ReaderAt, size int64) (*ExifData, error) {
+	var data *ExifData
+	var xmp []byte
+
+	offset := int64(2)
+	marker := make([]byte, 4)
+scan:
+	for offset+4 <= size {
+		if _, err := r.ReadAt(marker, offset); err != nil {
+			return nil, err
+		}
+		if marker[0] != 0xFF {
+			return nil, fmt.Errorf("無效的 JPEG 區段位移: %d", offset)
+		}
+
+		switch {
+		case marker[1] == 0xFF:
+			// 填充位元組
+			offset++
+			continue
+		case marker[1] == 0x01 || (marker[1] >= 0xD0 && marker[1] <= 0xD7):
+			// 沒有長度欄位的區段
+			offset += 2
+			continue
+		case marker[1] == 0xDA || marker[1] == 0xD9:
+			// 影像資料開始，EXIF 與 XMP 只會出現在這之前
+			break scan
+		}
+
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+		if length < 2 || offset+2+length > size {
+			return nil, fmt.Errorf("無效的 JPEG 區段長度: %d", length)
+		}
+
+		if marker[1] == 0xE1 {
+			start, end := offset+4, offset+2+length
+			switch {
+			case data == nil && hasSegmentHeader(r, start, end, exifHeader):
+				start += int64(len(exifHeader))
+				decoded, err := decodeTIFF(io.NewSectionReader(r, start, end-start), end-start)
+				if err != nil {
+					return nil, err
+				}
+				data = decoded
+			case xmp == nil && hasSegmentHeader(r, start, end, xmpHeader):
+				start += int64(len(xmpHeader))
+				xmp = make([]byte, end-start)
+				if _, err := r.ReadAt(xmp, start); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		offset += 2 + length
+	}
+
+	if xmp != nil {
+		if data == nil {
+			data = &ExifData{}
+		}
+		applyXMP(data, xmp)
+	}
+	if data == nil {
+		return nil, ErrNoExif
+	}
+	return data, nil
+}
+
+// hasSegmentHeader 區段內容是否以 header 開頭
+func hasSegmentHeader(r io.ReaderAt, start, end int64, header []byte) bool {
+	if end-start <= int64(len(header)) {
+		return false
+	}
+	buf := make([]byte, len(header))
+	if _, err := r.ReadAt(buf, start); err != nil {
+		return false
+	}
+	return bytes.Equal(buf, header)
+}
+
+// hasMetadata 是否有任何可用於分類的欄位
+func (e *ExifData) hasMetadata() bool {
+	return e.DateTimeOriginal != "" || e.CreationDate != "" || e.CreateDate != "" || e.MediaCreateDate != "" ||
+		e.Model != "" || e.GPSLatitude != "" || e.Rating != 0 || len(e.Keywords) > 0
+}
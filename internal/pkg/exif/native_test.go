@@ -0,0 +1,209 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testEntry 建立測試用 TIFF 的欄位，value 為 string（ASCII）或 []uint32（LONG/RATIONAL）
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	value interface{}
+}
+
+// buildTIFF 依序建立 IFD0、Exif IFD、GPS IFD，並自動在 IFD0 加上指標欄位
+func buildTIFF(order binary.ByteOrder, ifd0, exifIFD, gpsIFD []testEntry) []byte {
+	ifds := [][]testEntry{append([]testEntry{}, ifd0...), exifIFD, gpsIFD}
+	if len(exifIFD) > 0 {
+		ifds[0] = append(ifds[0], testEntry{tagExifIFD, typeLong, []uint32{0}})
+	}
+	if len(gpsIFD) > 0 {
+		ifds[0] = append(ifds[0], testEntry{tagGPSIFD, typeLong, []uint32{0}})
+	}
+
+	// 計算每個 IFD 的位移
+	offsets := make([]uint32, len(ifds))
+	pos := uint32(8)
+	for i, ifd := range ifds {
+		if i > 0 && len(ifd) == 0 {
+			continue
+		}
+		offsets[i] = pos
+		pos += uint32(2 + 12*len(ifd) + 4)
+	}
+	for i := range ifds[0] {
+		switch ifds[0][i].tag {
+		case tagExifIFD:
+			ifds[0][i].value = []uint32{offsets[1]}
+		case tagGPSIFD:
+			ifds[0][i].value = []uint32{offsets[2]}
+		}
+	}
+
+	var head, data bytes.Buffer
+	dataStart := pos
+	if order == binary.LittleEndian {
+		head.WriteString("II")
+	} else {
+		head.WriteString("MM")
+	}
+	binary.Write(&head, order, uint16(42))
+	binary.Write(&head, order, uint32(8))
+
+	for i, ifd := range ifds {
+		if i > 0 && len(ifd) == 0 {
+			continue
+		}
+		binary.Write(&head, order, uint16(len(ifd)))
+		for _, e := range ifd {
+			var value bytes.Buffer
+			count := uint32(0)
+			switch v := e.value.(type) {
+			case string:
+				value.WriteString(v)
+				value.WriteByte(0)
+				count = uint32(value.Len())
+			case []uint32:
+				for _, n := range v {
+					binary.Write(&value, order, n)
+				}
+				count = uint32(len(v))
+				if e.typ == typeRational {
+					count /= 2
+				}
+			}
+
+			binary.Write(&head, order, e.tag)
+			binary.Write(&head, order, e.typ)
+			binary.Write(&head, order, count)
+			if value.Len() <= 4 {
+				field := make([]byte, 4)
+				copy(field, value.Bytes())
+				head.Write(field)
+			} else {
+				binary.Write(&head, order, dataStart+uint32(data.Len()))
+				data.Write(value.Bytes())
+			}
+		}
+		binary.Write(&head, order, uint32(0))
+	}
+
+	return append(head.Bytes(), data.Bytes()...)
+}
+
+// sampleTIFF 含有日期、裝置與 GPS 的測試資料
+func sampleTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order,
+		[]testEntry{
+			{tagMake, typeASCII, "Apple"},
+			{tagModel, typeASCII, "iPhone 11"},
+		},
+		[]testEntry{
+			{tagDateTimeOriginal, typeASCII, "2024:05:03 10:20:30"},
+			{tagCreateDate, typeASCII, "2024:05:03 10:20:31"},
+			{tagOffsetTimeOriginal, typeASCII, "+08:00"},
+		},
+		[]testEntry{
+			{tagGPSLatitudeRef, typeASCII, "N"},
+			{tagGPSLatitude, typeRational, []uint32{25, 1, 2, 1, 1080, 100}},
+			{tagGPSLongitudeRef, typeASCII, "E"},
+			{tagGPSLongitude, typeRational, []uint32{121, 1, 33, 1, 5544, 100}},
+			{tagGPSTimeStamp, typeRational, []uint32{2, 1, 20, 1, 2950, 100}},
+			{tagGPSDateStamp, typeASCII, "2024:05:03"},
+		},
+	)
+}
+
+// wrapJPEG 將 TIFF 資料包進 JPEG 的 APP1 區段
+func wrapJPEG(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	// APP0 JFIF
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x07, 'J', 'F', 'I', 'F', 0x00})
+	buf.Write([]byte{0xFF, 0xE1})
+	binary.Write(&buf, binary.BigEndian, uint16(2+len(exifHeader)+len(tiff)))
+	buf.Write(exifHeader)
+	buf.Write(tiff)
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func TestDecodeNative(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"TIFF little endian", sampleTIFF(binary.LittleEndian)},
+		{"TIFF big endian", sampleTIFF(binary.BigEndian)},
+		{"JPEG", wrapJPEG(sampleTIFF(binary.BigEndian))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := decodeNative(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if err != nil {
+				t.Fatalf("解析失敗: %v", err)
+			}
+			if data.Make != "Apple" || data.Model != "iPhone 11" {
+				t.Errorf("裝置不符: %s %s", data.Make, data.Model)
+			}
+			if data.DateTimeOriginal != "2024:05:03 10:20:30" || data.CreateDate != "2024:05:03 10:20:31" {
+				t.Errorf("日期不符: %s %s", data.DateTimeOriginal, data.CreateDate)
+			}
+			if data.OffsetTimeOriginal != "+08:00" || data.GPSDateStamp != "2024:05:03" || data.GPSTimeStamp != "02:20:29" {
+				t.Errorf("時區欄位不符: %s %s %s", data.OffsetTimeOriginal, data.GPSDateStamp, data.GPSTimeStamp)
+			}
+			if data.GPSLatitude != `25 deg 2' 10.80" N` || data.GPSLongitude != `121 deg 33' 55.44" E` {
+				t.Errorf("GPS 不符: %s %s", data.GPSLatitude, data.GPSLongitude)
+			}
+
+			lat, err := ParseGPSString(data.GPSLatitude)
+			if err != nil || lat < 25.036 || lat > 25.037 {
+				t.Errorf("緯度轉換不符: %v %v", lat, err)
+			}
+		})
+	}
+}
+
+func TestDecodeNativeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"PNG", []byte("\x89PNG\r\n\x1a\n")},
+		{"沒有 EXIF 的 JPEG", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9}},
+		{"沒有欄位的 TIFF", buildTIFF(binary.LittleEndian, nil, nil, nil)},
+		{"截斷的 TIFF", sampleTIFF(binary.LittleEndian)[:20]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeNative(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Error("應回傳錯誤")
+			}
+		})
+	}
+}
+
+func FuzzDecodeTIFF(f *testing.F) {
+	f.Add(sampleTIFF(binary.LittleEndian))
+	f.Add(sampleTIFF(binary.BigEndian))
+	f.Add(buildTIFF(binary.LittleEndian, []testEntry{{tagModel, typeASCII, "X"}}, nil, nil))
+	f.Add([]byte("II*\x00\x08\x00\x00\x00\xff\xff"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeTIFF(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+func FuzzDecodeNative(f *testing.F) {
+	f.Add(wrapJPEG(sampleTIFF(binary.BigEndian)))
+	f.Add(sampleTIFF(binary.LittleEndian))
+	f.Add([]byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x08, 'E', 'x', 'i', 'f', 0, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeNative(bytes.NewReader(data), int64(len(data)))
+	})
+}
@@ -0,0 +1,281 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// macEpochOffset QuickTime 時間以 1904-01-01 UTC 為起點，與 Unix epoch 相差的秒數
+	macEpochOffset = 2082844800
+	// maxMetaSize moov/meta 與 udta 最多讀取的位元組數
+	maxMetaSize = 1024 * 1024
+)
+
+// Apple QuickTime metadata keys
+const (
+	keyCreationDate = "com.apple.quicktime.creationdate"
+	keyMake         = "com.apple.quicktime.make"
+	keyModel        = "com.apple.quicktime.model"
+	keyLocation     = "com.apple.quicktime.location.ISO6709"
+	keyContentID    = "com.apple.quicktime.content.identifier"
+)
+
+// iso6709Pattern ISO 6709 座標字串，例如 "+25.0330+121.5654+010.000/"
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)`)
+
+// decodeQuickTime 解析 MP4/MOV：mvhd/mdhd 建立時間、Apple meta keys 與 udta 裝置字串
+func decodeQuickTime(r io.ReaderAt, size int64) (*ExifData, error) {
+	moov, err := findBox(r, 0, size, "moov")
+	if err != nil {
+		return nil, ErrUnsupportedNative
+	}
+
+	data := &ExifData{}
+	children, _ := readBoxes(r, moov.offset, moov.offset+moov.size)
+	for _, child := range children {
+		switch child.typ {
+		case "mvhd":
+			setIfEmpty(&data.CreateDate, readMediaTime(r, child))
+		case "trak":
+			if mdia, err := findBox(r, child.offset, child.offset+child.size, "mdia"); err == nil {
+				if mdhd, err := findBox(r, mdia.offset, mdia.offset+mdia.size, "mdhd"); err == nil {
+					setIfEmpty(&data.MediaCreateDate, readMediaTime(r, mdhd))
+				}
+			}
+		case "meta":
+			applyQuickTimeKeys(data, r, child)
+		case "udta":
+			applyUserData(data, r, child)
+		}
+	}
+
+	return data, nil
+}
+
+// readMediaTime 讀取 mvhd/mdhd 的 creation_time，轉成 exiftool 的 UTC 時間格式
+func readMediaTime(r io.ReaderAt, box bmffBox) string {
+	header, err := readBoxData(r, box, 1024)
+	if err != nil || len(header) < 8 {
+		return ""
+	}
+
+	var seconds uint64
+	if header[0] == 1 {
+		if len(header) < 12 {
+			return ""
+		}
+		seconds = binary.BigEndian.Uint64(header[4:])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(header[4:]))
+	}
+
+	// 未設定時間的檔案為 0
+	if seconds <= macEpochOffset || seconds > math.MaxInt64 {
+		return ""
+	}
+	return time.Unix(int64(seconds-macEpochOffset), 0).UTC().Format(exifTimeLayout)
+}
+
+// applyQuickTimeKeys 解析 moov/meta 中 keys 與 ilst 的 Apple 中繼資料
+func applyQuickTimeKeys(data *ExifData, r io.ReaderAt, meta bmffBox) {
+	payload, err := readBoxData(r, meta, maxMetaSize)
+	if err != nil {
+		return
+	}
+	children := readMetaChildren(payload)
+
+	var keys []string
+	var ilst []byte
+	for _, child := range children {
+		body := payload[child.offset : child.offset+child.size]
+		switch child.typ {
+		case "keys":
+			keys = parseKeys(body)
+		case "ilst":
+			ilst = body
+		}
+	}
+	if keys == nil || ilst == nil {
+		return
+	}
+
+	items, _ := readBoxes(bytes.NewReader(ilst), 0, int64(len(ilst)))
+	for _, item := range items {
+		index := binary.BigEndian.Uint32([]byte(item.typ))
+		if index == 0 || int(index) > len(keys) {
+			continue
+		}
+		value := readDataAtom(ilst[item.offset : item.offset+item.size])
+
+		switch keys[index-1] {
+		case keyCreationDate:
+			setIfEmpty(&data.CreationDate, formatISO8601(value))
+		case keyMake:
+			setIfEmpty(&data.Make, value)
+		case keyModel:
+			setIfEmpty(&data.Model, value)
+		case keyLocation:
+			applyISO6709(data, value)
+		case keyContentID:
+			setIfEmpty(&data.ContentIdentifier, value)
+		}
+	}
+}
+
+// readMetaChildren 讀取 meta 的子 box；QuickTime 的 meta 不是 FullBox，MP4 的則是
+func readMetaChildren(payload []byte) []bmffBox {
+	r := bytes.NewReader(payload)
+	if children, err := readBoxes(r, 0, int64(len(payload))); err == nil && len(children) > 0 && children[0].typ == "hdlr" {
+		return children
+	}
+	children, _ := readBoxes(r, 4, int64(len(payload)))
+	return children
+}
+
+// parseKeys 解析 keys box，回傳依索引排列的 key 名稱
+func parseKeys(body []byte) []string {
+	if len(body) < 8 {
+		return nil
+	}
+	count := binary.BigEndian.Uint32(body[4:])
+	keys := make([]string, 0, min(count, maxBoxes))
+	offset := 8
+	for i := uint32(0); i < count && offset+8 <= len(body); i++ {
+		size := int(binary.BigEndian.Uint32(body[offset:]))
+		if size < 8 || offset+size > len(body) {
+			break
+		}
+		keys = append(keys, string(body[offset+8:offset+size]))
+		offset += size
+	}
+	return keys
+}
+
+// readDataAtom 讀取 ilst 項目中 data atom 的 UTF-8 值
+func readDataAtom(item []byte) string {
+	atom, err := findBox(bytes.NewReader(item), 0, int64(len(item)), "data")
+	if err != nil || atom.size < 8 {
+		return ""
+	}
+	// data atom 前 8 個位元組為型別與語系
+	return strings.TrimSpace(string(item[atom.offset+8 : atom.offset+atom.size]))
+}
+
+// applyUserData 解析 udta 中的裝置與位置字串（DJI、Android 等）與 GoPro 的 GPMF
+func applyUserData(data *ExifData, r io.ReaderAt, udta bmffBox) {
+	payload, err := readBoxData(r, udta, maxMetaSize)
+	if err != nil {
+		return
+	}
+	children, _ := readBoxes(bytes.NewReader(payload), 0, int64(len(payload)))
+	for _, child := range children {
+		body := payload[child.offset : child.offset+child.size]
+		switch child.typ {
+		case "\xa9mak":
+			setIfEmpty(&data.Make, readUserDataText(body))
+		case "\xa9mdl":
+			setIfEmpty(&data.Model, readUserDataText(body))
+		case "\xa9xyz":
+			applyISO6709(data, readUserDataText(body))
+		case "GPMF":
+			if model := findGPMFString(body, "MINF", 0); model != "" {
+				setIfEmpty(&data.Make, "GoPro")
+				setIfEmpty(&data.Model, model)
+			}
+		}
+	}
+}
+
+// readUserDataText 讀取 QuickTime 使用者資料字串：16 位元長度、16 位元語系、內容
+func readUserDataText(body []byte) string {
+	if len(body) < 4 {
+		return ""
+	}
+	size := int(binary.BigEndian.Uint16(body))
+	text := body[4:]
+	if size < len(text) {
+		text = text[:size]
+	}
+	return strings.TrimSpace(strings.TrimRight(string(text), "\x00"))
+}
+
+// findGPMFString 在 GoPro GPMF KLV 結構中尋找字串型別的 key
+func findGPMFString(body []byte, key string, depth int) string {
+	if depth > 8 {
+		return ""
+	}
+	for offset := 0; offset+8 <= len(body); {
+		klvKey := string(body[offset : offset+4])
+		typ := body[offset+4]
+		length := int(body[offset+5]) * int(binary.BigEndian.Uint16(body[offset+6:]))
+		start := offset + 8
+		if start+length > len(body) {
+			return ""
+		}
+		value := body[start : start+length]
+
+		switch {
+		case klvKey == key && typ == 'c':
+			return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+		case typ == 0:
+			// 巢狀結構
+			if s := findGPMFString(value, key, depth+1); s != "" {
+				return s
+			}
+		}
+
+		// 資料長度對齊 4 位元組
+		offset = start + (length+3)&^3
+	}
+	return ""
+}
+
+// applyISO6709 將 ISO 6709 座標轉成與 exiftool 相同的度分秒字串
+func applyISO6709(data *ExifData, value string) {
+	match := iso6709Pattern.FindStringSubmatch(value)
+	if match == nil {
+		return
+	}
+	lat, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return
+	}
+	lon, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return
+	}
+	data.setDecimalGPS(lat, lon)
+}
+
+// iso8601Layouts Apple 影片與 XMP 使用的 ISO 8601 格式，zone 表示是否帶有時區
+var iso8601Layouts = []struct {
+	layout string
+	zone   bool
+}{
+	{"2006-01-02T15:04:05-0700", true},
+	{time.RFC3339, true},
+	{"2006-01-02T15:04Z07:00", true},
+	{"2006-01-02T15:04:05", false},
+	{"2006-01-02T15:04", false},
+	{"2006-01-02", false},
+}
+
+// formatISO8601 將 ISO 8601 時間轉成 exiftool 的格式，保留時區
+func formatISO8601(value string) string {
+	for _, l := range iso8601Layouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			if !l.zone {
+				return t.Format(exifTimeLayout)
+			}
+			return t.Format("2006:01:02 15:04:05-07:00")
+		}
+	}
+	return ""
+}
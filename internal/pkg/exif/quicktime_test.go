@@ -0,0 +1,127 @@
+package exif
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mvhdBox 建立 version 0 的 mvhd/mdhd，時間以 1904 年為起點
+func mvhdBox(typ string, t time.Time) []byte {
+	return mkfullbox(typ, 0, be32(uint32(t.Unix()+macEpochOffset)), be32(0), be32(600), be32(0), make([]byte, 80))
+}
+
+// keysMeta 建立 Apple keys/ilst 中繼資料
+func keysMeta(entries map[string]string) []byte {
+	var keys, ilst [][]byte
+	index := uint32(0)
+	for _, key := range []string{keyCreationDate, keyMake, keyModel, keyLocation} {
+		value, ok := entries[key]
+		if !ok {
+			continue
+		}
+		index++
+		keys = append(keys, append(be32(uint32(8+len(key))), append([]byte("mdta"), key...)...))
+		ilst = append(ilst, mkbox(string(be32(index)), mkbox("data", be32(1), be32(0), []byte(value))))
+	}
+	return mkbox("meta",
+		mkfullbox("hdlr", 0, be32(0), []byte("mdta"), make([]byte, 13)),
+		mkfullbox("keys", 0, be32(index), bytes.Join(keys, nil)),
+		mkbox("ilst", ilst...),
+	)
+}
+
+// userDataText 建立 udta 中的 QuickTime 字串
+func userDataText(typ, value string) []byte {
+	return mkbox(typ, be16(uint16(len(value))), be16(0x15c7), []byte(value))
+}
+
+func TestDecodeQuickTime(t *testing.T) {
+	created := time.Date(2019, 2, 3, 2, 10, 10, 0, time.UTC)
+	minf := append([]byte("MINF"), 'c', 1, 0, 11)
+	minf = append(minf, []byte("HERO8 Black\x00")...)
+
+	tests := []struct {
+		name     string
+		file     []byte
+		expected ExifData
+	}{
+		{
+			name: "iPhone MOV",
+			file: append(mkbox("ftyp", []byte("qt  "), be32(0), []byte("qt  ")), mkbox("moov",
+				mvhdBox("mvhd", created),
+				mkbox("trak", mkbox("mdia", mvhdBox("mdhd", created.Add(time.Second)))),
+				keysMeta(map[string]string{
+					keyCreationDate: "2019-02-03T10:10:10+0800",
+					keyMake:         "Apple",
+					keyModel:        "iPhone 11",
+					keyLocation:     "+25.0330+121.5654+010.000/",
+				}),
+			)...),
+			expected: ExifData{
+				CreationDate:    "2019:02:03 10:10:10+08:00",
+				CreateDate:      "2019:02:03 02:10:10",
+				MediaCreateDate: "2019:02:03 02:10:11",
+				Make:            "Apple",
+				Model:           "iPhone 11",
+				GPSLatitude:     `25 deg 1' 58.80" N`,
+				GPSLongitude:    `121 deg 33' 55.44" E`,
+			},
+		},
+		{
+			name: "GoPro MP4",
+			file: append(mkbox("ftyp", []byte("mp41"), be32(0), []byte("mp41")), mkbox("moov",
+				mvhdBox("mvhd", created),
+				mkbox("udta", mkbox("FIRM", []byte("HD8.01.02.51.00")), mkbox("GPMF", minf)),
+			)...),
+			expected: ExifData{
+				CreateDate: "2019:02:03 02:10:10",
+				Make:       "GoPro",
+				Model:      "HERO8 Black",
+			},
+		},
+		{
+			name: "DJI MOV 沒有 ftyp",
+			file: mkbox("moov",
+				mvhdBox("mvhd", created),
+				mkbox("udta", userDataText("\xa9mdl", "FC3170"), userDataText("\xa9xyz", "-33.8688+151.2093+5.0/")),
+			),
+			expected: ExifData{
+				CreateDate:   "2019:02:03 02:10:10",
+				Model:        "FC3170",
+				GPSLatitude:  `33 deg 52' 7.68" S`,
+				GPSLongitude: `151 deg 12' 33.48" E`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := decodeNative(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if err != nil {
+				t.Fatalf("解析失敗: %v", err)
+			}
+			if !reflect.DeepEqual(*data, tt.expected) {
+				t.Errorf("資料不符\n期望: %+v\n得到: %+v", tt.expected, *data)
+			}
+		})
+	}
+}
+
+func TestReadMediaTimeUnset(t *testing.T) {
+	file := mkbox("moov", mkfullbox("mvhd", 0, be32(0), make([]byte, 92)))
+	if _, err := decodeNative(bytes.NewReader(file), int64(len(file))); err == nil {
+		t.Error("沒有建立時間的影片應回傳錯誤，交給 exiftool 處理")
+	}
+}
+
+func FuzzDecodeQuickTime(f *testing.F) {
+	created := time.Date(2019, 2, 3, 2, 10, 10, 0, time.UTC)
+	f.Add(mkbox("moov", mvhdBox("mvhd", created), keysMeta(map[string]string{keyModel: "iPhone 11"})))
+	f.Add(mkbox("moov", mkbox("udta", mkbox("GPMF", []byte("DEVC\x00\x04\x00\x02MINFc\x01\x00\x04HERO")))))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeQuickTime(bytes.NewReader(data), int64(len(data)))
+	})
+}
@@ -0,0 +1,74 @@
+package exif
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestGetTargetPathRename(t *testing.T) {
+	srcDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(srcDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	first := write("IMG_0001.JPG", "first")
+	second := write("IMG_0002.JPG", "second")
+	burst := write("IMG_0003.JPG", "burst")
+
+	cfg := &config.Config{
+		SrcDir:         srcDir,
+		DstDir:         t.TempDir(),
+		DateFormat:     "2006-01",
+		RenameTemplate: `{{.Date "20060102_150405"}}{{.SubSec | printf "_%s"}}_{{.Device}}{{.Seq}}`,
+	}
+	data := func(path string) *ExifData {
+		return &ExifData{SourceFile: path, DateTimeOriginal: "2024:05:03 10:20:30", SubSecTimeOriginal: "25", Model: "ILCE-7M3"}
+	}
+	copyTo := func(path string) string {
+		target, err := GetTargetPath(path, data(path), cfg, nil)
+		if err != nil {
+			t.Fatalf("取得目標路徑失敗: %v", err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Base(target)
+	}
+
+	if got := copyTo(first); got != "20240503_102030_250_ILCE7M3.JPG" {
+		t.Errorf("檔名不符: %s", got)
+	}
+	// 同一秒的另一個檔案以內容雜湊區分
+	secondName := copyTo(second)
+	if secondName == "20240503_102030_250_ILCE7M3.JPG" || filepath.Ext(secondName) != ".JPG" {
+		t.Errorf("衝突的檔名應加上雜湊: %s", secondName)
+	}
+	// 重新執行時得到相同的檔名
+	if got := copyTo(first); got != "20240503_102030_250_ILCE7M3.JPG" {
+		t.Errorf("重新執行檔名不同: %s", got)
+	}
+	if got := copyTo(second); got != secondName {
+		t.Errorf("重新執行檔名不同: 期望 %s，得到 %s", secondName, got)
+	}
+
+	// 連拍序號
+	burstData := data(burst)
+	burstData.SequenceNumber = 3
+	target, err := GetTargetPath(burst, burstData, cfg, nil)
+	if err != nil {
+		t.Fatalf("取得目標路徑失敗: %v", err)
+	}
+	if got := filepath.Base(target); got != "20240503_102030_250_ILCE7M3_003.JPG" {
+		t.Errorf("連拍檔名不符: %s", got)
+	}
+}
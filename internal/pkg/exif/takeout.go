@@ -0,0 +1,166 @@
+package exif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+const (
+	// takeoutNameLimit Google Takeout sidecar 檔名（不含 .json）最長的字元數，超過會被截斷
+	takeoutNameLimit = 46
+	// takeoutSupplemental 新版 Takeout 的 sidecar 會在媒體檔名後加上這個字串
+	takeoutSupplemental = "supplemental-metadata"
+	// takeoutEditedSuffix Google 相簿編輯過的檔案與原檔共用 sidecar
+	takeoutEditedSuffix = "-edited"
+)
+
+// takeoutCounterPattern 重複檔名的編號，例如 IMG_1234(1).jpg 的 sidecar 為 IMG_1234.jpg(1).json
+var takeoutCounterPattern = regexp.MustCompile(`^(.*)(\(\d+\))$`)
+
+// ErrNotTakeout JSON 不是 Google Takeout 的 sidecar
+var ErrNotTakeout = errors.New("不是 Google Takeout sidecar")
+
+// takeoutMetadata Google Takeout sidecar 中會用到的欄位
+type takeoutMetadata struct {
+	Title          string       `json:"title"`
+	PhotoTakenTime *takeoutTime `json:"photoTakenTime"`
+	GeoData        *takeoutGeo  `json:"geoData"`
+	GeoDataExif    *takeoutGeo  `json:"geoDataExif"`
+}
+
+// takeoutTime Takeout 的時間，timestamp 為 Unix 秒數字串
+type takeoutTime struct {
+	Timestamp string `json:"timestamp"`
+}
+
+// takeoutGeo Takeout 的座標，沒有位置時為 0
+type takeoutGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// listJSONNames 取得資料夾中所有 .json 檔名
+func listJSONNames(dir string, dirs *DirCache) []string {
+	var names []string
+	for _, name := range dirs.list(dir) {
+		if strings.EqualFold(filepath.Ext(name), ".json") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// findJSONSidecar 找出媒體檔案的 JSON sidecar，處理 Takeout 的檔名截斷、(1) 編號與 -edited
+func findJSONSidecar(path string, dirs *DirCache) string {
+	dir, name := filepath.Split(path)
+	if match := matchJSONSidecar(name, listJSONNames(filepath.Clean(dir), dirs)); match != "" {
+		return filepath.Join(dir, match)
+	}
+	return ""
+}
+
+// matchJSONSidecar 在 candidates 中找出 name 的 sidecar，有多個符合時取最長的
+func matchJSONSidecar(name string, candidates []string) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	counter := ""
+	if match := takeoutCounterPattern.FindStringSubmatch(stem); match != nil {
+		stem, counter = match[1], match[2]
+	}
+	stem = strings.TrimSuffix(stem, takeoutEditedSuffix)
+	full := stem + ext
+
+	best, bestLen := "", 0
+	for _, candidate := range candidates {
+		base := candidate[:len(candidate)-len(".json")]
+		if !strings.HasSuffix(base, counter) {
+			continue
+		}
+		base = strings.TrimSuffix(base, counter)
+
+		// 新版的 .supplemental-metadata 也可能被截斷
+		if strings.HasPrefix(base, full+".") && strings.HasPrefix(takeoutSupplemental, base[len(full)+1:]) {
+			base = full
+		}
+
+		if base != full {
+			// 被截斷的檔名至少要保留副檔名前的點，或是已達長度上限
+			if !strings.HasPrefix(full, base) || (len(base) <= len(stem) && len(base) < takeoutNameLimit) {
+				continue
+			}
+		}
+		if len(base) > bestLen {
+			best, bestLen = candidate, len(base)
+		}
+	}
+	return best
+}
+
+// parseTakeout 解析 Google Takeout sidecar，沒有 photoTakenTime 與 geoData 的 JSON 不視為 sidecar
+func parseTakeout(content []byte) (*takeoutMetadata, error) {
+	var meta takeoutMetadata
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return nil, ErrNotTakeout
+	}
+	if meta.PhotoTakenTime == nil && meta.GeoData == nil {
+		return nil, ErrNotTakeout
+	}
+	return &meta, nil
+}
+
+// exifData 將 Takeout 的拍攝時間與座標轉成 ExifData。photoTakenTime 是不知道拍攝地時區的 UTC 時間，
+// 放在一律視為 UTC 的 MediaCreateDate，由 GPS 所在地的時區或 folder_time_zone 決定分類的日期
+func (m *takeoutMetadata) exifData(path string) (*ExifData, error) {
+	data := &ExifData{SourceFile: path}
+	if m.PhotoTakenTime != nil {
+		seconds, err := strconv.ParseInt(m.PhotoTakenTime.Timestamp, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("解析 photoTakenTime 失敗: %v", err)
+		}
+		if seconds > 0 {
+			data.MediaCreateDate = time.Unix(seconds, 0).UTC().Format(exifTimeLayout)
+		}
+	}
+
+	// geoData 為使用者在 Google 相簿修改過的位置，沒有時使用原始 EXIF 的位置
+	for _, geo := range []*takeoutGeo{m.GeoData, m.GeoDataExif} {
+		if geo != nil && (geo.Latitude != 0 || geo.Longitude != 0) {
+			data.setDecimalGPS(geo.Latitude, geo.Longitude)
+			break
+		}
+	}
+	return data, nil
+}
+
+// IsTakeoutSidecar 判斷 JSON 是否為旁邊某個要處理的媒體檔的 sidecar，這類檔案跟著媒體檔處理；
+// 只比對資料夾中的檔名，不讀取 JSON，找不到媒體檔的 JSON 照一般檔案處理
+func IsTakeoutSidecar(path string, cfg *config.Config, dirs *DirCache) bool {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		return false
+	}
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+	candidates := listJSONNames(dir, dirs)
+	for _, media := range dirs.list(dir) {
+		if !cfg.IsSupportedFormat(media) || cfg.ShouldIgnore(media) {
+			continue
+		}
+		if matchJSONSidecar(media, candidates) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTakeoutSidecar 找出媒體檔案對應的 JSON sidecar，沒有時回傳空字串
+func FindTakeoutSidecar(path string, dirs *DirCache) string {
+	return findJSONSidecar(path, dirs)
+}
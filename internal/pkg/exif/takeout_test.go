@@ -0,0 +1,176 @@
+package exif
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+	"photo-sorter/internal/pkg/geocoding"
+)
+
+func TestMatchJSONSidecar(t *testing.T) {
+	candidates := []string{
+		"IMG_1234.jpg.json",
+		"IMG_1234.jpg(1).json",
+		"IMG_5678.j.json",
+		"PXL_20240101_123456789.jpg.supplemental-metad.json",
+		"Screenshot_2023-05-14-10-11-12-123_com.google.a.json",
+		"metadata.json",
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"IMG_1234.jpg", "IMG_1234.jpg.json"},
+		{"IMG_1234(1).jpg", "IMG_1234.jpg(1).json"},
+		{"IMG_1234-edited.jpg", "IMG_1234.jpg.json"},
+		{"IMG_5678.jpg", "IMG_5678.j.json"},
+		{"PXL_20240101_123456789.jpg", "PXL_20240101_123456789.jpg.supplemental-metad.json"},
+		{"Screenshot_2023-05-14-10-11-12-123_com.google.android.apps.photos.png", "Screenshot_2023-05-14-10-11-12-123_com.google.a.json"},
+		{"IMG_12.jpg", ""},
+		{"IMG_1234(2).jpg", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchJSONSidecar(tt.name, candidates); got != tt.expected {
+				t.Errorf("期望 %q，得到 %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSidecarExtractorTakeout(t *testing.T) {
+	dir := t.TempDir()
+	media := filepath.Join(dir, "IMG_5678.jpg")
+	sidecar := filepath.Join(dir, "IMG_5678.j.json")
+	if err := os.WriteFile(media, []byte{0xFF, 0xD8}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	taken := time.Date(2019, 5, 14, 8, 4, 16, 0, time.UTC)
+	content := `{"title":"IMG_5678.jpg","photoTakenTime":{"timestamp":"` + strconv.FormatInt(taken.Unix(), 10) + `"},` +
+		`"geoData":{"latitude":0,"longitude":0},"geoDataExif":{"latitude":25.033,"longitude":121.5654}}`
+	if err := os.WriteFile(sidecar, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Formats: []string{".jpg"}}
+	if !IsTakeoutSidecar(sidecar, cfg, nil) || IsTakeoutSidecar(media, cfg, nil) {
+		t.Error("sidecar 判斷錯誤")
+	}
+	if got := FindTakeoutSidecar(media, nil); got != sidecar {
+		t.Errorf("找不到 sidecar: %q", got)
+	}
+
+	result, err := (&SidecarExtractor{}).Extract([]string{media})
+	if err != nil {
+		t.Fatalf("擷取失敗: %v", err)
+	}
+	data := result[media]
+	if data == nil {
+		t.Fatal("應取得中繼資料")
+	}
+	if expected := "2019:05:14 08:04:16"; data.MediaCreateDate != expected || data.DateTimeOriginal != "" {
+		t.Errorf("拍攝時間不符: 期望 UTC %s，得到 %s / %s", expected, data.MediaCreateDate, data.DateTimeOriginal)
+	}
+	if data.GPSLatitude != `25 deg 1' 58.80" N` || data.GPSLongitude != `121 deg 33' 55.44" E` {
+		t.Errorf("GPS 不符: %s %s", data.GPSLatitude, data.GPSLongitude)
+	}
+}
+
+func TestIsTakeoutSidecar(t *testing.T) {
+	dir := t.TempDir()
+	// 只比對檔名，JSON 內容不是 Takeout 格式也不影響判斷
+	for _, name := range []string{
+		"IMG_1234.jpg", "IMG_1234.jpg.json", "IMG_1234(1).jpg", "IMG_1234.jpg(1).json",
+		"metadata.json", "IMG_9999.jpg.json", "notes.txt", "notes.txt.json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{Formats: []string{".jpg"}}
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"IMG_1234.jpg.json", true},
+		{"IMG_1234.jpg(1).json", true},
+		{"metadata.json", false},
+		{"IMG_9999.jpg.json", false},
+		{"notes.txt.json", false},
+		{"IMG_1234.jpg", false},
+	}
+
+	dirs := NewDirCache()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTakeoutSidecar(filepath.Join(dir, tt.name), cfg, dirs); got != tt.expected {
+				t.Errorf("期望 %v，得到 %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTakeoutLocalDayFolder(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "timezones.geojson")
+	content := `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"tzid": "Asia/Taipei"},
+		"geometry": {"type": "Polygon", "coordinates": [[[119, 21], [123, 21], [123, 26], [119, 26], [119, 21]]]}}]}`
+	if err := os.WriteFile(jsonPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	timeZones, err := geocoding.NewGeoTimeZone(jsonPath)
+	if err != nil {
+		t.Fatalf("載入時區邊界失敗: %v", err)
+	}
+
+	// UTC 2019-05-14 20:30 在台北已是 5 月 15 日
+	media := filepath.Join(dir, "IMG_0001.jpg")
+	taken := time.Date(2019, 5, 14, 20, 30, 0, 0, time.UTC)
+	sidecar := `{"title":"IMG_0001.jpg","photoTakenTime":{"timestamp":"` + strconv.FormatInt(taken.Unix(), 10) + `"},` +
+		`"geoData":{"latitude":25.033,"longitude":121.5654}}`
+	if err := os.WriteFile(media, []byte{0xFF, 0xD8}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(media+".json", []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.Config
+		timeZones   geocoding.TimeZoneGeocoder
+		expected    string
+		zoneUnknown bool
+	}{
+		{"以 GPS 所在地的時區分類", &config.Config{}, timeZones, "2019-05-15", false},
+		{"以 folder_time_zone 分類", &config.Config{FolderTimeZone: "Asia/Taipei"}, nil, "2019-05-15", false},
+		{"時區未知時使用 UTC", &config.Config{}, nil, "2019-05-14", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := (&SidecarExtractor{}).Extract([]string{media})
+			if err != nil {
+				t.Fatalf("擷取失敗: %v", err)
+			}
+			data := result[media]
+			tt.cfg.SrcDir, tt.cfg.DstDir, tt.cfg.DateFormat = dir, t.TempDir(), "2006-01-02"
+			target, err := GetTargetPath(media, data, tt.cfg, tt.timeZones)
+			if err != nil {
+				t.Fatalf("取得目標路徑失敗: %v", err)
+			}
+			if got := filepath.Base(filepath.Dir(filepath.Dir(target))); got != tt.expected {
+				t.Errorf("日期資料夾不符: 期望 %s，得到 %s", tt.expected, got)
+			}
+			if data.ZoneUnknown != tt.zoneUnknown {
+				t.Errorf("ZoneUnknown = %v, want %v", data.ZoneUnknown, tt.zoneUnknown)
+			}
+		})
+	}
+}
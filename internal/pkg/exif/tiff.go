@@ -0,0 +1,396 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// IFD0 標籤
+const (
+	tagMake    = 0x010F
+	tagModel   = 0x0110
+	tagXMP     = 0x02BC
+	tagExifIFD = 0x8769
+	tagGPSIFD  = 0x8825
+)
+
+// Exif IFD 標籤
+const (
+	tagDateTimeOriginal   = 0x9003
+	tagCreateDate         = 0x9004
+	tagOffsetTimeOriginal = 0x9011
+	tagPixelXDimension    = 0xA002
+	tagPixelYDimension    = 0xA003
+	tagSubSecTimeOriginal = 0x9291
+	tagBodySerialNumber   = 0xA431
+	tagLensModel          = 0xA434
+	tagMakerNote          = 0x927C
+)
+
+// Apple MakerNote 標籤
+const (
+	tagAppleBurstUUID         = 0x000B
+	tagAppleContentIdentifier = 0x0011
+)
+
+// appleMakerNoteHeader iPhone MakerNote 的開頭，接著是版本與 "MM"，IFD 從第 14 個位元組開始
+var appleMakerNoteHeader = []byte("Apple iOS\x00")
+
+// GPS IFD 標籤
+const (
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSTimeStamp    = 0x0007
+	tagGPSDateStamp    = 0x001D
+)
+
+// TIFF 欄位型別
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeSByte     = 6
+	typeUndefined = 7
+	typeSShort    = 8
+	typeSLong     = 9
+	typeSRational = 10
+	typeFloat     = 11
+	typeDouble    = 12
+	typeIFD       = 13
+)
+
+// typeSizes 各型別單一元素的位元組數
+var typeSizes = map[uint16]uint64{
+	typeByte:      1,
+	typeASCII:     1,
+	typeShort:     2,
+	typeLong:      4,
+	typeRational:  8,
+	typeSByte:     1,
+	typeUndefined: 1,
+	typeSShort:    2,
+	typeSLong:     4,
+	typeSRational: 8,
+	typeFloat:     4,
+	typeDouble:    8,
+	typeIFD:       4,
+}
+
+const (
+	// maxIFDEntries 單一 IFD 最多讀取的欄位數，避免損毀檔案造成大量配置
+	maxIFDEntries = 1024
+	// maxValueSize 單一欄位最多讀取的位元組數
+	maxValueSize = 64 * 1024
+)
+
+var errNotTIFF = errors.New("不是 TIFF 格式")
+
+// tiffReader 透過 io.ReaderAt 讀取 TIFF 結構，RAW 檔不需要整個讀進記憶體
+type tiffReader struct {
+	r     io.ReaderAt
+	size  int64
+	order binary.ByteOrder
+}
+
+// ifdEntry IFD 中的單一欄位
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+	order binary.ByteOrder
+}
+
+// ifdKind TIFF 第一個 IFD 的內容類型，CR3 會把各個 IFD 拆成獨立的 TIFF 結構
+type ifdKind int
+
+const (
+	// ifdKindMain 一般的 IFD0，會再跟著指標讀取 Exif IFD 與 GPS IFD
+	ifdKindMain ifdKind = iota
+	// ifdKindExif 第一個 IFD 即為 Exif IFD
+	ifdKindExif
+	// ifdKindGPS 第一個 IFD 即為 GPS IFD
+	ifdKindGPS
+)
+
+// decodeTIFF 解析 TIFF 標頭與 IFD0、Exif IFD、GPS IFD
+func decodeTIFF(r io.ReaderAt, size int64) (*ExifData, error) {
+	data := &ExifData{}
+	if err := decodeTIFFInto(data, r, size, ifdKindMain); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeTIFFInto 解析 TIFF 並依 kind 將第一個 IFD 的欄位填入 data
+func decodeTIFFInto(data *ExifData, r io.ReaderAt, size int64, kind ifdKind) error {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return errNotTIFF
+	}
+
+	t := &tiffReader{r: r, size: size}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return errNotTIFF
+	}
+	if t.order.Uint16(header[2:]) != 42 {
+		return errNotTIFF
+	}
+
+	first, err := t.readIFD(int64(t.order.Uint32(header[4:])))
+	if err != nil {
+		return fmt.Errorf("讀取 IFD0 失敗: %v", err)
+	}
+
+	switch kind {
+	case ifdKindExif:
+		applyExifIFD(data, first)
+	case ifdKindGPS:
+		applyGPSIFD(data, first)
+	default:
+		applyIFD0(data, first)
+		if offset, ok := first[tagExifIFD].uint(0); ok {
+			if exifIFD, err := t.readIFD(int64(offset)); err == nil {
+				applyExifIFD(data, exifIFD)
+			}
+		}
+		if offset, ok := first[tagGPSIFD].uint(0); ok {
+			if gpsIFD, err := t.readIFD(int64(offset)); err == nil {
+				applyGPSIFD(data, gpsIFD)
+			}
+		}
+		// DNG 與部分 RAW 會把 XMP 放在 IFD0
+		if xmp := first[tagXMP]; xmp != nil {
+			applyXMP(data, xmp.value)
+		}
+	}
+
+	return nil
+}
+
+// applyIFD0 填入 IFD0 的裝置資訊
+func applyIFD0(data *ExifData, ifd map[uint16]*ifdEntry) {
+	setIfEmpty(&data.Make, ifd[tagMake].ascii())
+	setIfEmpty(&data.Model, ifd[tagModel].ascii())
+}
+
+// applyExifIFD 填入 Exif IFD 的拍攝時間、機身序號、鏡頭與影像尺寸
+func applyExifIFD(data *ExifData, ifd map[uint16]*ifdEntry) {
+	setIfEmpty(&data.DateTimeOriginal, ifd[tagDateTimeOriginal].ascii())
+	setIfEmpty(&data.OffsetTimeOriginal, ifd[tagOffsetTimeOriginal].ascii())
+	setIfEmpty(&data.SubSecTimeOriginal, ifd[tagSubSecTimeOriginal].ascii())
+	setIfEmpty(&data.CreateDate, ifd[tagCreateDate].ascii())
+	setIfEmpty(&data.SerialNumber, ifd[tagBodySerialNumber].ascii())
+	setIfEmpty(&data.LensModel, ifd[tagLensModel].ascii())
+	if width, ok := ifd[tagPixelXDimension].uint(0); ok && data.ImageWidth == 0 {
+		data.ImageWidth = int(width)
+	}
+	if height, ok := ifd[tagPixelYDimension].uint(0); ok && data.ImageHeight == 0 {
+		data.ImageHeight = int(height)
+	}
+	if note := ifd[tagMakerNote]; note != nil {
+		applyAppleMakerNote(data, note.value)
+	}
+}
+
+// applyAppleMakerNote 讀取 iPhone MakerNote 中 Live Photo 的 ContentIdentifier 與連拍的 BurstUUID，位移以 MakerNote 開頭為起點
+func applyAppleMakerNote(data *ExifData, note []byte) {
+	if !bytes.HasPrefix(note, appleMakerNoteHeader) || len(note) < 16 || string(note[12:14]) != "MM" {
+		return
+	}
+	t := &tiffReader{r: bytes.NewReader(note), size: int64(len(note)), order: binary.BigEndian}
+	ifd, err := t.readIFD(14)
+	if err != nil {
+		return
+	}
+	setIfEmpty(&data.ContentIdentifier, ifd[tagAppleContentIdentifier].ascii())
+	setIfEmpty(&data.BurstUUID, ifd[tagAppleBurstUUID].ascii())
+}
+
+// applyGPSIFD 填入 GPS IFD 的經緯度
+func applyGPSIFD(data *ExifData, ifd map[uint16]*ifdEntry) {
+	setIfEmpty(&data.GPSLatitude, formatGPS(ifd[tagGPSLatitude].rationals(), ifd[tagGPSLatitudeRef].ascii()))
+	setIfEmpty(&data.GPSLongitude, formatGPS(ifd[tagGPSLongitude].rationals(), ifd[tagGPSLongitudeRef].ascii()))
+	setIfEmpty(&data.GPSDateStamp, ifd[tagGPSDateStamp].ascii())
+	setIfEmpty(&data.GPSTimeStamp, formatGPSTime(ifd[tagGPSTimeStamp].rationals()))
+}
+
+// setIfEmpty 只在欄位尚未有值時填入
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// readIFD 讀取指定位移的 IFD，回傳標籤到欄位的對應
+func (t *tiffReader) readIFD(offset int64) (map[uint16]*ifdEntry, error) {
+	if offset < 8 || offset+2 > t.size {
+		return nil, fmt.Errorf("IFD 位移超出範圍: %d", offset)
+	}
+
+	buf := make([]byte, 2)
+	if _, err := t.r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+	count := int64(t.order.Uint16(buf))
+	if count > maxIFDEntries {
+		return nil, fmt.Errorf("IFD 欄位數過多: %d", count)
+	}
+	if offset+2+count*12 > t.size {
+		return nil, fmt.Errorf("IFD 超出檔案範圍")
+	}
+
+	raw := make([]byte, count*12)
+	if _, err := t.r.ReadAt(raw, offset+2); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[uint16]*ifdEntry, count)
+	for i := int64(0); i < count; i++ {
+		e := raw[i*12 : i*12+12]
+		tag := t.order.Uint16(e[0:])
+		typ := t.order.Uint16(e[2:])
+		n := t.order.Uint32(e[4:])
+
+		unit, ok := typeSizes[typ]
+		if !ok {
+			continue
+		}
+		size := unit * uint64(n)
+		if size > maxValueSize {
+			continue
+		}
+
+		value := make([]byte, size)
+		if size <= 4 {
+			copy(value, e[8:8+size])
+		} else {
+			valueOffset := int64(t.order.Uint32(e[8:]))
+			if valueOffset+int64(size) > t.size {
+				continue
+			}
+			if _, err := t.r.ReadAt(value, valueOffset); err != nil {
+				continue
+			}
+		}
+
+		entries[tag] = &ifdEntry{typ: typ, count: n, value: value, order: t.order}
+	}
+
+	return entries, nil
+}
+
+// ascii 取得 ASCII 欄位的字串值
+func (e *ifdEntry) ascii() string {
+	if e == nil || (e.typ != typeASCII && e.typ != typeUndefined) {
+		return ""
+	}
+	s := string(e.value)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// uint 取得 SHORT/LONG 欄位第 i 個值
+func (e *ifdEntry) uint(i int) (uint32, bool) {
+	if e == nil || i < 0 || uint32(i) >= e.count {
+		return 0, false
+	}
+	switch e.typ {
+	case typeShort:
+		return uint32(e.order.Uint16(e.value[i*2:])), true
+	case typeLong, typeIFD:
+		return e.order.Uint32(e.value[i*4:]), true
+	}
+	return 0, false
+}
+
+// rationals 取得 RATIONAL 欄位的所有值
+func (e *ifdEntry) rationals() []float64 {
+	if e == nil || (e.typ != typeRational && e.typ != typeSRational) {
+		return nil
+	}
+	values := make([]float64, 0, e.count)
+	for i := uint32(0); i < e.count; i++ {
+		num := e.order.Uint32(e.value[i*8:])
+		den := e.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			values = append(values, 0)
+			continue
+		}
+		if e.typ == typeSRational {
+			values = append(values, float64(int32(num))/float64(int32(den)))
+		} else {
+			values = append(values, float64(num)/float64(den))
+		}
+	}
+	return values
+}
+
+// formatGPS 將度分秒有理數轉成與 exiftool 相同的字串格式，
+// 例如 "22 deg 41' 58.80\" N"，讓 ParseGPSString 可以直接使用
+func formatGPS(dms []float64, ref string) string {
+	if len(dms) != 3 {
+		return ""
+	}
+	decimal := math.Abs(dms[0]) + math.Abs(dms[1])/60 + math.Abs(dms[2])/3600
+	if math.IsNaN(decimal) || math.IsInf(decimal, 0) || decimal > 180 {
+		return ""
+	}
+
+	// 以 0.01 秒為單位取整後再拆回度分秒，避免出現 60.00 秒
+	centiSeconds := int64(math.Round(decimal * 3600 * 100))
+	degrees := centiSeconds / (3600 * 100)
+	minutes := centiSeconds / (60 * 100) % 60
+	seconds := float64(centiSeconds%(60*100)) / 100
+
+	s := fmt.Sprintf("%d deg %d' %.2f\"", degrees, minutes, seconds)
+	if ref != "" {
+		s += " " + ref
+	}
+	return s
+}
+
+// formatGPSTime 將 GPS 時間的時、分、秒有理數轉成與 exiftool 相同的 "15:04:05" 格式
+func formatGPSTime(hms []float64) string {
+	if len(hms) != 3 {
+		return ""
+	}
+	seconds := hms[0]*3600 + hms[1]*60 + hms[2]
+	if math.IsNaN(seconds) || seconds < 0 || seconds >= 24*3600 {
+		return ""
+	}
+	total := int(seconds)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+// setDecimalGPS 將十進位座標轉成與 exiftool 相同的度分秒字串，只填入尚未有值的欄位
+func (e *ExifData) setDecimalGPS(lat, lon float64) {
+	if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return
+	}
+
+	latRef, lonRef := "N", "E"
+	if lat < 0 {
+		latRef = "S"
+	}
+	if lon < 0 {
+		lonRef = "W"
+	}
+	setIfEmpty(&e.GPSLatitude, formatGPS([]float64{math.Abs(lat), 0, 0}, latRef))
+	setIfEmpty(&e.GPSLongitude, formatGPS([]float64{math.Abs(lon), 0, 0}, lonRef))
+}
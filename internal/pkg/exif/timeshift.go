@@ -0,0 +1,54 @@
+package exif
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+// CorrectCaptureTime 依 time_corrections 修正相機時鐘的誤差，並記錄套用的位移
+func (e *ExifData) CorrectCaptureTime(capture CaptureTime, cfg *config.Config) CaptureTime {
+	shift, ok := cfg.TimeShift(e.Model, e.SerialNumber, capture.Local())
+	if !ok || shift == 0 {
+		return capture
+	}
+	e.TimeShift = shift
+	capture.Time = capture.Time.Add(shift)
+	return capture
+}
+
+// WriteTimeShift 以 exiftool 將檔案中繼資料中的時間加上 shift，影片另外修正 QuickTime 的媒體與軌道時間
+func WriteTimeShift(path string, shift time.Duration) error {
+	tags := []string{"AllDates"}
+	if quickTimeExts[strings.ToLower(filepath.Ext(path))] {
+		tags = append(tags, "MediaCreateDate", "MediaModifyDate", "TrackCreateDate", "TrackModifyDate", "Keys:CreationDate")
+	}
+
+	op, value := exiftoolShift(shift)
+	args := []string{"-m", "-overwrite_original"}
+	for _, tag := range tags {
+		args = append(args, "-"+tag+op+value)
+	}
+	args = append(args, path)
+
+	output, err := exec.Command("exiftool", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("寫入拍攝時間失敗: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// exiftoolShift 將位移轉成 exiftool 的 "+=" 或 "-=" 與 "Y:M:D h:m:s" 格式，捨去不足一秒的部分
+func exiftoolShift(shift time.Duration) (op, value string) {
+	op = "+="
+	if shift < 0 {
+		op, shift = "-=", -shift
+	}
+	seconds := int64(shift / time.Second)
+	days := seconds / (24 * 3600)
+	return op, fmt.Sprintf("0:0:%d %d:%d:%d", days, seconds/3600%24, seconds/60%60, seconds%60)
+}
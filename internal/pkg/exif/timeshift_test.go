@@ -0,0 +1,79 @@
+package exif
+
+import (
+	"testing"
+	"time"
+
+	"photo-sorter/internal/pkg/config"
+)
+
+func TestCorrectCaptureTime(t *testing.T) {
+	cfg := &config.Config{TimeCorrections: []config.TimeCorrection{
+		{Model: "ILCE-7M3", SerialNumber: "1234", Shift: "-1h"},
+		{Model: "ILCE-7M3", From: "2024-07-01", To: "2024-07-14", Shift: "1h7m"},
+	}}
+
+	tests := []struct {
+		name     string
+		data     ExifData
+		shift    time.Duration
+		expected string
+	}{
+		{
+			name:     "日期範圍內的型號",
+			data:     ExifData{Model: "ILCE-7M3", DateTimeOriginal: "2024:07:14 23:30:00"},
+			shift:    67 * time.Minute,
+			expected: "2024-07-15 00:37:00",
+		},
+		{
+			name:     "日期範圍外不修正",
+			data:     ExifData{Model: "ILCE-7M3", DateTimeOriginal: "2024:07:15 00:00:00"},
+			expected: "2024-07-15 00:00:00",
+		},
+		{
+			name:     "序號優先比對",
+			data:     ExifData{Model: "ilce-7m3", SerialNumber: "1234", DateTimeOriginal: "2024:07:10 12:00:00"},
+			shift:    -time.Hour,
+			expected: "2024-07-10 11:00:00",
+		},
+		{
+			name:     "不同型號不修正",
+			data:     ExifData{Model: "iPhone 15", DateTimeOriginal: "2024:07:10 12:00:00"},
+			expected: "2024-07-10 12:00:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture, err := tt.data.ResolveCaptureTime()
+			if err != nil {
+				t.Fatalf("解析拍攝時間失敗: %v", err)
+			}
+			capture = tt.data.CorrectCaptureTime(capture, cfg)
+			if tt.data.TimeShift != tt.shift {
+				t.Errorf("位移不符: 期望 %s，得到 %s", tt.shift, tt.data.TimeShift)
+			}
+			if got := capture.Local().Format("2006-01-02 15:04:05"); got != tt.expected {
+				t.Errorf("時間不符: 期望 %s，得到 %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExiftoolShift(t *testing.T) {
+	tests := []struct {
+		shift time.Duration
+		op    string
+		value string
+	}{
+		{67 * time.Minute, "+=", "0:0:0 1:7:0"},
+		{-(26*time.Hour + 30*time.Second), "-=", "0:0:1 2:0:30"},
+	}
+
+	for _, tt := range tests {
+		op, value := exiftoolShift(tt.shift)
+		if op != tt.op || value != tt.value {
+			t.Errorf("%s: 期望 %s%s，得到 %s%s", tt.shift, tt.op, tt.value, op, value)
+		}
+	}
+}
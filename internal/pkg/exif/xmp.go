@@ -0,0 +1,238 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// XMP 命名空間
+const (
+	nsRDF       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsXMP       = "http://ns.adobe.com/xap/1.0/"
+	nsPhotoshop = "http://ns.adobe.com/photoshop/1.0/"
+	nsEXIF      = "http://ns.adobe.com/exif/1.0/"
+	nsTIFF      = "http://ns.adobe.com/tiff/1.0/"
+	nsDC        = "http://purl.org/dc/elements/1.1/"
+	nsAux       = "http://ns.adobe.com/exif/1.0/aux/"
+	nsExifEX    = "http://cipa.jp/exif/1.0/"
+)
+
+// maxXMPSize sidecar 最多讀取的位元組數
+const maxXMPSize = 4 * 1024 * 1024
+
+// xmpHeader JPEG APP1 區段中 XMP 資料的開頭
+var xmpHeader = []byte(nsXMP + "\x00")
+
+// decodeXMP 解析 XMP packet，屬性可以寫成 rdf:Description 的 attribute 或子元素
+func decodeXMP(packet []byte) (*ExifData, error) {
+	props := make(map[xml.Name]string)
+	var keywords []string
+	var stack []xml.Name
+
+	// JPEG 與 TIFF 內嵌的 XMP 後面可能補零
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimRight(packet, "\x00")))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 XMP 失敗: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space == nsRDF && t.Name.Local == "Description" {
+				for _, attr := range t.Attr {
+					if _, ok := props[attr.Name]; !ok {
+						props[attr.Name] = strings.TrimSpace(attr.Value)
+					}
+				}
+			}
+			stack = append(stack, t.Name)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" || len(stack) == 0 {
+				continue
+			}
+			// rdf:Bag、rdf:Seq、rdf:Alt 的值屬於最近的非 RDF 元素
+			prop := stack[len(stack)-1]
+			for i := len(stack) - 1; i >= 0 && prop.Space == nsRDF; i-- {
+				prop = stack[i]
+			}
+			if prop == (xml.Name{Space: nsDC, Local: "subject"}) {
+				keywords = append(keywords, text)
+			} else if _, ok := props[prop]; !ok {
+				props[prop] = text
+			}
+		}
+	}
+
+	get := func(space, local string) string {
+		return props[xml.Name{Space: space, Local: local}]
+	}
+	data := &ExifData{
+		DateTimeOriginal: formatISO8601(get(nsEXIF, "DateTimeOriginal")),
+		CreateDate:       formatISO8601(get(nsXMP, "CreateDate")),
+		Make:             get(nsTIFF, "Make"),
+		Model:            get(nsTIFF, "Model"),
+		SerialNumber:     get(nsAux, "SerialNumber"),
+		LensModel:        get(nsExifEX, "LensModel"),
+		GPSLatitude:      formatXMPGPS(get(nsEXIF, "GPSLatitude")),
+		GPSLongitude:     formatXMPGPS(get(nsEXIF, "GPSLongitude")),
+		Keywords:         keywords,
+	}
+	setIfEmpty(&data.LensModel, get(nsAux, "Lens"))
+	// Lightroom 以 photoshop:DateCreated 記錄修正後的拍攝時間
+	setIfEmpty(&data.DateTimeOriginal, formatISO8601(get(nsPhotoshop, "DateCreated")))
+	if rating, err := strconv.Atoi(get(nsXMP, "Rating")); err == nil {
+		data.Rating = rating
+	}
+	return data, nil
+}
+
+// applyXMP 以內嵌的 XMP 補齊 EXIF 沒有的欄位
+func applyXMP(data *ExifData, packet []byte) {
+	if xmp, err := decodeXMP(packet); err == nil {
+		data.fill(xmp)
+	}
+}
+
+// formatXMPGPS 將 XMP 的 "25,1.98N" 或 "25,1,58.8N" 轉成與 exiftool 相同的度分秒字串
+func formatXMPGPS(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return ""
+	}
+	ref := strings.ToUpper(value[len(value)-1:])
+	if !strings.Contains("NSEW", ref) {
+		return ""
+	}
+
+	parts := strings.Split(value[:len(value)-1], ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ""
+	}
+	dms := make([]float64, 3)
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return ""
+		}
+		dms[i] = n
+	}
+	return formatGPS(dms, ref)
+}
+
+// isXMPName 是否為 .xmp 副檔名
+func isXMPName(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".xmp")
+}
+
+// FindXMPSidecar 找出媒體檔案的 XMP sidecar，優先使用 darktable 的 IMG_1234.CR2.xmp，
+// 其次是 Lightroom 的 IMG_1234.xmp，沒有時回傳空字串
+func FindXMPSidecar(path string, dirs *DirCache) string {
+	dir, name := filepath.Split(path)
+	stem := strings.TrimSuffix(name, filepath.Ext(name))
+
+	var fallback string
+	for _, candidate := range dirs.list(filepath.Clean(dir)) {
+		if !isXMPName(candidate) {
+			continue
+		}
+		base := strings.TrimSuffix(candidate, filepath.Ext(candidate))
+		if strings.EqualFold(base, name) {
+			return filepath.Join(dir, candidate)
+		}
+		if strings.EqualFold(base, stem) {
+			fallback = filepath.Join(dir, candidate)
+		}
+	}
+	return fallback
+}
+
+// IsXMPSidecar 判斷檔案是否為旁邊某個檔案的 XMP sidecar，這類檔案不當成媒體處理
+func IsXMPSidecar(path string, dirs *DirCache) bool {
+	if !isXMPName(path) {
+		return false
+	}
+	dir, name := filepath.Split(path)
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+
+	for _, candidate := range dirs.list(filepath.Clean(dir)) {
+		if isXMPName(candidate) {
+			continue
+		}
+		if strings.EqualFold(candidate, base) || strings.EqualFold(strings.TrimSuffix(candidate, filepath.Ext(candidate)), base) {
+			return true
+		}
+	}
+	return false
+}
+
+// XMPExtractor 讀取 XMP sidecar，排在 native 前面時 sidecar 的值優先
+type XMPExtractor struct {
+	dirs *DirCache
+}
+
+// Name 擷取器名稱
+func (x *XMPExtractor) Name() string {
+	return string(XMPExtractorType)
+}
+
+// Extract 讀取每個檔案的 XMP sidecar
+func (x *XMPExtractor) Extract(paths []string) (map[string]*ExifData, error) {
+	result := make(map[string]*ExifData, len(paths))
+	var errs []error
+	for _, path := range paths {
+		sidecar := FindXMPSidecar(path, x.dirs)
+		if sidecar == "" {
+			continue
+		}
+		packet, err := readXMPSidecar(sidecar)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		data, err := decodeXMP(packet)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", sidecar, err))
+			continue
+		}
+		if data.hasMetadata() {
+			data.SourceFile = path
+			result[path] = data
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// readXMPSidecar 讀取 sidecar 內容，過大的檔案視為錯誤
+func readXMPSidecar(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	packet, err := io.ReadAll(io.LimitReader(f, maxXMPSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("讀取 XMP sidecar 失敗: %v", err)
+	}
+	if len(packet) > maxXMPSize {
+		return nil, fmt.Errorf("XMP sidecar 過大: %s", path)
+	}
+	return packet, nil
+}
@@ -0,0 +1,130 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sampleXMP Lightroom 風格的 sidecar，日期與評分寫在 attribute，關鍵字寫在 rdf:Bag
+const sampleXMP = `<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:photoshop="http://ns.adobe.com/photoshop/1.0/"
+    xmlns:exif="http://ns.adobe.com/exif/1.0/"
+    xmlns:tiff="http://ns.adobe.com/tiff/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmp:CreateDate="2021-07-08T09:10:11.25+02:00"
+    photoshop:DateCreated="2021-07-08T09:10:11"
+    xmp:Rating="4"
+    tiff:Model="Canon EOS R5">
+   <exif:GPSLatitude>25,1.98N</exif:GPSLatitude>
+   <exif:GPSLongitude>121,33,55.44E</exif:GPSLongitude>
+   <dc:subject>
+    <rdf:Bag>
+     <rdf:li>family</rdf:li>
+     <rdf:li>beach</rdf:li>
+    </rdf:Bag>
+   </dc:subject>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func TestDecodeXMP(t *testing.T) {
+	data, err := decodeXMP([]byte(sampleXMP))
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+
+	expected := ExifData{
+		DateTimeOriginal: "2021:07:08 09:10:11",
+		CreateDate:       "2021:07:08 09:10:11+02:00",
+		Model:            "Canon EOS R5",
+		GPSLatitude:      `25 deg 1' 58.80" N`,
+		GPSLongitude:     `121 deg 33' 55.44" E`,
+		Rating:           4,
+		Keywords:         []string{"family", "beach"},
+	}
+	if !reflect.DeepEqual(*data, expected) {
+		t.Errorf("資料不符\n期望: %+v\n得到: %+v", expected, *data)
+	}
+}
+
+func TestDecodeJPEGEmbeddedXMP(t *testing.T) {
+	// EXIF 沒有的欄位由 XMP 補齊，EXIF 已有的欄位不被覆蓋
+	tiff := buildTIFF(binary.BigEndian, []testEntry{{tagModel, typeASCII, "iPhone 11"}}, nil, nil)
+	jpeg := wrapJPEG(tiff)
+	var segment bytes.Buffer
+	segment.Write([]byte{0xFF, 0xE1})
+	binary.Write(&segment, binary.BigEndian, uint16(2+len(xmpHeader)+len(sampleXMP)))
+	segment.Write(xmpHeader)
+	segment.WriteString(sampleXMP)
+	// 插在 SOS 之前
+	sos := bytes.Index(jpeg, []byte{0xFF, 0xDA})
+	jpeg = append(append(append([]byte{}, jpeg[:sos]...), segment.Bytes()...), jpeg[sos:]...)
+
+	data, err := decodeNative(bytes.NewReader(jpeg), int64(len(jpeg)))
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if data.Model != "iPhone 11" || data.DateTimeOriginal != "2021:07:08 09:10:11" || data.Rating != 4 {
+		t.Errorf("資料不符: %+v", *data)
+	}
+}
+
+func TestXMPSidecar(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"IMG_0001.CR2", "IMG_0001.CR2.xmp", "IMG_0001.xmp", "IMG_0002.NEF", "IMG_0002.XMP", "orphan.xmp"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(sampleXMP), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"IMG_0001.CR2", "IMG_0001.CR2.xmp"},
+		{"IMG_0002.NEF", "IMG_0002.XMP"},
+		{"IMG_0003.NEF", ""},
+	}
+	for _, tt := range tests {
+		got := FindXMPSidecar(filepath.Join(dir, tt.path), nil)
+		if tt.expected != "" {
+			tt.expected = filepath.Join(dir, tt.expected)
+		}
+		if got != tt.expected {
+			t.Errorf("%s 的 sidecar 應為 %q，得到 %q", tt.path, tt.expected, got)
+		}
+	}
+
+	if !IsXMPSidecar(filepath.Join(dir, "IMG_0001.xmp"), nil) || !IsXMPSidecar(filepath.Join(dir, "IMG_0002.XMP"), nil) {
+		t.Error("有對應媒體檔的 XMP 應視為 sidecar")
+	}
+	if IsXMPSidecar(filepath.Join(dir, "orphan.xmp"), nil) {
+		t.Error("沒有對應媒體檔的 XMP 不應視為 sidecar")
+	}
+
+	result, err := (&XMPExtractor{}).Extract([]string{filepath.Join(dir, "IMG_0002.NEF")})
+	if err != nil {
+		t.Fatalf("擷取失敗: %v", err)
+	}
+	if data := result[filepath.Join(dir, "IMG_0002.NEF")]; data == nil || data.Model != "Canon EOS R5" {
+		t.Errorf("應從 sidecar 取得資料: %+v", data)
+	}
+}
+
+func FuzzDecodeXMP(f *testing.F) {
+	f.Add([]byte(sampleXMP))
+	f.Add([]byte(`<rdf:Description xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"/>`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeXMP(data)
+	})
+}
@@ -0,0 +1,16 @@
+package geocoding
+
+import "math"
+
+// earthRadiusKM 地球平均半徑（公里）
+const earthRadiusKM = 6371.0
+
+// DistanceKM 以半正矢公式計算兩個座標之間的大圓距離（公里）
+func DistanceKM(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
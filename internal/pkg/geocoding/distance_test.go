@@ -0,0 +1,28 @@
+package geocoding
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKM(t *testing.T) {
+	tests := []struct {
+		name               string
+		lat1, lon1         float64
+		lat2, lon2         float64
+		expected, accuracy float64
+	}{
+		{"同一點", 25.033, 121.5654, 25.033, 121.5654, 0, 0.001},
+		{"台北到東京", 25.033, 121.5654, 35.6762, 139.6503, 2100, 20},
+		{"跨越換日線", 0, 179.5, 0, -179.5, 111, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DistanceKM(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.expected) > tt.accuracy {
+				t.Errorf("DistanceKM() = %.1f, want %.1f ± %.1f", got, tt.expected, tt.accuracy)
+			}
+		})
+	}
+}
@@ -34,23 +34,34 @@ func NewGeoState(jsonPath string) (*GeoState, error) {
 
 // loadGeoJSON 載入 GeoJSON 資料
 func (g *GeoState) loadGeoJSON() error {
-	jsonFile, err := os.Open(g.jsonPath)
+	collection, err := loadGeoJSONCollection(g.jsonPath)
 	if err != nil {
 		return err
 	}
+	g.collection = collection
+	return nil
+}
+
+// loadGeoJSONCollection 讀取並解析 GeoJSON 檔案
+func loadGeoJSONCollection(jsonPath string) (*GeoJSONCollection, error) {
+	jsonFile, err := os.Open(jsonPath)
+	if err != nil {
+		return nil, err
+	}
 	defer jsonFile.Close()
 
 	byteValue, err := io.ReadAll(jsonFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	g.collection = &GeoJSONCollection{}
-	if err := json.Unmarshal(byteValue, g.collection); err != nil {
-		return err
+	collection := &GeoJSONCollection{}
+	if err := json.Unmarshal(byteValue, collection); err != nil {
+		return nil, err
 	}
+	collection.prepare()
 
-	return nil
+	return collection, nil
 }
 
 type GeoJSONFeature struct {
@@ -60,16 +71,53 @@ type GeoJSONFeature struct {
 		Name   string `json:"name"`
 		Admin  string `json:"admin"`
 		Adm0A3 string `json:"adm0_a3"`
+		Region string `json:"region"`
+		TZID   string `json:"tzid"`
 	} `json:"properties"`
 	Geometry struct {
 		Type        string          `json:"type"`
 		Coordinates json.RawMessage `json:"coordinates"`
 	} `json:"geometry"`
+
+	// 載入時解析的多邊形與整個 feature 的外接矩形
+	polygons []polygon
+	bbox     bbox
+}
+
+// contains 座標是否在任一多邊形內
+func (f *GeoJSONFeature) contains(lat, lon float64) bool {
+	if !f.bbox.contains(lat, lon) {
+		return false
+	}
+	for _, p := range f.polygons {
+		if p.contains(lat, lon) {
+			return true
+		}
+	}
+	return false
 }
 
 type GeoJSONCollection struct {
 	Type     string           `json:"type"`
 	Features []GeoJSONFeature `json:"features"`
+
+	index *gridIndex
+}
+
+// prepare 解析每個 feature 的座標並建立空間索引，查詢時不需要再反序列化，
+// 無法解析的 feature 沒有多邊形，不會被找到
+func (c *GeoJSONCollection) prepare() {
+	for i := range c.Features {
+		feature := &c.Features[i]
+		feature.polygons, _ = decodeGeometry(feature.Geometry.Type, feature.Geometry.Coordinates)
+		feature.bbox = emptyBBox()
+		for _, p := range feature.polygons {
+			feature.bbox.extend(p[0].bbox)
+		}
+		// 原始座標已經解析完，釋放記憶體
+		feature.Geometry.Coordinates = nil
+	}
+	c.index = newGridIndex(c.Features)
 }
 
 func (g *GeoState) GetLocationFromGPS(lat, lon float64) (*CountryCity, error) {
@@ -77,44 +125,28 @@ func (g *GeoState) GetLocationFromGPS(lat, lon float64) (*CountryCity, error) {
 		return nil, errors.New("GeoJSON 資料未載入")
 	}
 
-	// 檢查每個多邊形是否包含給定的座標
-	for _, feature := range g.collection.Features {
-		switch feature.Geometry.Type {
-		case "Polygon":
-			var coordinates [][][]float64
-			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
-				continue
-			}
-			if len(coordinates) > 0 && len(coordinates[0]) > 0 {
-				if isPointInPolygon(lat, lon, coordinates[0]) {
-					countryCity := &CountryCity{
-						Country: feature.Properties.Adm0A3,
-						City:    feature.Properties.Name,
-					}
-					return countryCity, nil
-				}
-			}
-		case "MultiPolygon":
-			var coordinates [][][][]float64
-			if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil {
-				continue
-			}
-			// 檢查每個多邊形
-			for _, polygon := range coordinates {
-				if len(polygon) > 0 && len(polygon[0]) > 0 {
-					if isPointInPolygon(lat, lon, polygon[0]) {
-						countryCity := &CountryCity{
-							Country: feature.Properties.Adm0A3,
-							City:    feature.Properties.Name,
-						}
-						return countryCity, nil
-					}
-				}
-			}
-		}
+	feature := g.collection.findFeature(lat, lon)
+	if feature == nil {
+		return nil, errors.New("location not found")
 	}
+	return &CountryCity{
+		Country: feature.Properties.Adm0A3,
+		Region:  feature.Properties.Region,
+		City:    feature.Properties.Name,
+	}, nil
+}
 
-	return nil, errors.New("location not found")
+// findFeature 找出包含給定座標的第一個 feature
+func (c *GeoJSONCollection) findFeature(lat, lon float64) *GeoJSONFeature {
+	if c.index == nil {
+		return nil
+	}
+	for _, i := range c.index.candidates(lat, lon) {
+		if feature := &c.Features[i]; feature.contains(lat, lon) {
+			return feature
+		}
+	}
+	return nil
 }
 
 // isPointInPolygon 使用射線法判斷點是否在多邊形內
@@ -4,13 +4,16 @@ import (
 	"errors"
 )
 
+// Geocoder 依 GPS 座標查詢地點，同一個實例由所有 worker 共用，實作需可同時查詢
 type Geocoder interface {
 	GetLocationFromGPS(lat, lon float64) (*CountryCity, error)
 }
 
 type CountryCity struct {
 	Country string
+	Region  string
 	City    string
+	Place   string // 使用者自訂的地點名稱，不在任何自訂地點內時為空
 }
 
 // GeocoderType 定義地理編碼器的類型
@@ -24,16 +27,26 @@ const (
 
 // NewGeocoder 建立一個新的 Geocoder 實例
 // geocoderType 指定要使用的地理編碼器類型
-// options 是建立地理編碼器時需要的選項
+// options 是建立地理編碼器時需要的選項，有 fences（[]*GeoFence）時先比對自訂地點
 func NewGeocoder(geocoderType GeocoderType, options map[string]interface{}) (Geocoder, error) {
+	var geocoder Geocoder
 	switch geocoderType {
 	case GeoStateType:
 		jsonPath, ok := options["json_path"].(string)
 		if !ok {
 			return nil, errors.New("json_path is required for GeoAlpha3JSON type")
 		}
-		return NewGeoState(jsonPath)
+		geoState, err := NewGeoState(jsonPath)
+		if err != nil {
+			return nil, err
+		}
+		geocoder = geoState
 	default:
 		return nil, errors.New("unsupported geocoder type")
 	}
+
+	if fences, ok := options["fences"].([]*GeoFence); ok && len(fences) > 0 {
+		return NewGeoFenceGeocoder(fences, geocoder), nil
+	}
+	return geocoder, nil
 }
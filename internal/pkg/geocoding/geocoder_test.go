@@ -1,16 +1,20 @@
 package geocoding
 
 import (
+	"math/rand"
 	"os"
+	"path/filepath"
 	"runtime/pprof"
 	"testing"
 )
 
-func TestGeocoderLocationMapping(t *testing.T) {
-	testJSONPath := "/Users/kimi/go/src/photo-sorter/geodata/states.geojson"
+// testStatesJSONPath 以幾個簡化的區域模擬 Natural Earth admin-1 資料，
+// 包含有洞的多邊形（南非中的賴索托、澎湖的內海）與跨越換日線的區域
+const testStatesJSONPath = "testdata/states.geojson"
 
+func TestGeocoderLocationMapping(t *testing.T) {
 	geocoder, err := NewGeocoder(GeoStateType, map[string]interface{}{
-		"json_path": testJSONPath,
+		"json_path": testStatesJSONPath,
 	})
 	if err != nil {
 		t.Fatalf("建立地理編碼器失敗: %v", err)
@@ -20,121 +24,133 @@ func TestGeocoderLocationMapping(t *testing.T) {
 		name     string
 		lat      float64
 		lon      float64
+		country  string
 		expected string
 	}{
-		{
-			name:     "台北",
-			lat:      25.0330,
-			lon:      121.5654,
-			expected: "New Taipei",
-		},
-		{
-			name:     "澎湖",
-			lat:      23.5494003,
-			lon:      119.5890471,
-			expected: "Penghu",
-		},
-		{
-			name:     "日本東京",
-			lat:      35.6895,
-			lon:      139.6917,
-			expected: "Tokyo",
-		},
-		{
-			name:     "美國紐約",
-			lat:      40.7128,
-			lon:      -74.0060,
-			expected: "New York",
-		},
-		{
-			name:     "英國倫敦",
-			lat:      51.5074,
-			lon:      -0.1278,
-			expected: "Westminster",
-		},
-		{
-			name:     "法國巴黎",
-			lat:      48.8566,
-			lon:      2.3522,
-			expected: "Paris",
-		},
-		{
-			name:     "德國柏林",
-			lat:      52.5200,
-			lon:      13.4050,
-			expected: "Brandenburg",
-		},
-		{
-			name:     "中國北京",
-			lat:      39.9384151,
-			lon:      116.0671435,
-			expected: "Beijing",
-		},
-		{
-			name:     "韓國首爾",
-			lat:      37.5665,
-			lon:      126.9780,
-			expected: "Gyeonggi",
-		},
-		{
-			name:     "澳洲雪梨",
-			lat:      -33.8688,
-			lon:      151.2093,
-			expected: "New South Wales",
-		},
+		{name: "台北", lat: 25.0330, lon: 121.5654, country: "TWN", expected: "Taipei"},
+		{name: "南非", lat: -26.2041, lon: 28.0473, country: "ZAF", expected: "Free State"},
+		{name: "被南非包圍的賴索托", lat: -29.3151, lon: 27.4869, country: "LSO", expected: "Maseru"},
+		{name: "澎湖本島", lat: 23.5494, lon: 119.5890, country: "TWN", expected: "Penghu"},
+		{name: "澎湖的內海不屬於任何區域", lat: 23.6, lon: 119.6},
+		{name: "澎湖的另一個島", lat: 23.25, lon: 119.35, country: "TWN", expected: "Penghu"},
+		{name: "斐濟換日線以西", lat: -17.5, lon: 178.4, country: "FJI", expected: "Northern"},
+		{name: "斐濟換日線以東", lat: -16.5, lon: -179.5, country: "FJI", expected: "Northern"},
+		{name: "楚科奇換日線以西", lat: 66, lon: 175, country: "RUS", expected: "Chukchi"},
+		{name: "楚科奇換日線以東", lat: 66, lon: -175, country: "RUS", expected: "Chukchi"},
+		{name: "與楚科奇同緯度的本初子午線", lat: 66, lon: 0},
+		{name: "南極點附近", lat: -85, lon: 45, country: "ATA", expected: "Antarctica"},
+		{name: "海上", lat: 0, lon: 0},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			location, err := geocoder.GetLocationFromGPS(tt.lat, tt.lon)
+			if tt.expected == "" {
+				if err == nil {
+					t.Errorf("應找不到位置，得到 %s-%s", location.Country, location.City)
+				}
+				return
+			}
 			if err != nil {
 				t.Errorf("取得位置失敗: %v", err)
 				return
 			}
 
-			if location.City != tt.expected {
-				t.Errorf("位置不匹配，期望 %s，得到 %s", tt.expected, location.City)
+			if location.Country != tt.country || location.City != tt.expected {
+				t.Errorf("位置不匹配，期望 %s-%s，得到 %s-%s", tt.country, tt.expected, location.Country, location.City)
 			}
 		})
 	}
 }
 
-func BenchmarkGetLocationFromGPS(b *testing.B) {
-	testJSONPath := "/Users/kimi/go/src/photo-sorter/geodata/states.geojson"
+// benchmarkLocations 效能測試使用的位置
+var benchmarkLocations = []struct {
+	name string
+	lat  float64
+	lon  float64
+}{
+	{"台北", 25.0330, 121.5654},
+	{"澎湖", 23.5494003, 119.5890471},
+	{"東京", 35.6895, 139.6917},
+	{"紐約", 40.7128, -74.0060},
+	{"倫敦", 51.5074, -0.1278},
+}
 
-	geocoder, err := NewGeocoder(GeoStateType, map[string]interface{}{
-		"json_path": testJSONPath,
-	})
-	if err != nil {
-		b.Fatalf("建立地理編碼器失敗: %v", err)
+// benchmarkDatasets 效能測試使用的資料：套件內附的國家邊界，以及 Natural Earth admin-1 的 states.geojson，
+// 後者以環境變數 GEOCODING_STATES_GEOJSON 指定路徑，預設為 `make data` 在專案根目錄產生的 geodata/states.geojson
+// （測試在套件資料夾中執行，因此是 ../../../geodata/states.geojson），檔案不存在時略過
+func benchmarkDatasets() []struct{ name, path string } {
+	datasets := []struct{ name, path string }{{"countries", countriesJSONPath}}
+	statesPath := os.Getenv("GEOCODING_STATES_GEOJSON")
+	if statesPath == "" {
+		statesPath = filepath.Join("..", "..", "..", "geodata", "states.geojson")
 	}
-
-	// 測試不同位置的效能
-	testCases := []struct {
-		name string
-		lat  float64
-		lon  float64
-	}{
-		{"台北", 25.0330, 121.5654},
-		{"澎湖", 23.5494003, 119.5890471},
-		{"東京", 35.6895, 139.6917},
-		{"紐約", 40.7128, -74.0060},
-		{"倫敦", 51.5074, -0.1278},
+	if _, err := os.Stat(statesPath); err == nil {
+		datasets = append(datasets, struct{ name, path string }{"states", statesPath})
 	}
+	return datasets
+}
 
-	for _, tc := range testCases {
-		b.Run(tc.name, func(b *testing.B) {
-			b.ResetTimer()
+func BenchmarkNewGeoState(b *testing.B) {
+	for _, dataset := range benchmarkDatasets() {
+		b.Run(dataset.name, func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
-				_, err := geocoder.GetLocationFromGPS(tc.lat, tc.lon)
-				if err != nil {
-					b.Fatalf("取得位置失敗: %v", err)
+				if _, err := NewGeoState(dataset.path); err != nil {
+					b.Fatalf("建立地理編碼器失敗: %v", err)
 				}
 			}
 		})
 	}
 }
 
+func BenchmarkGetLocationFromGPS(b *testing.B) {
+	for _, dataset := range benchmarkDatasets() {
+		geocoder, err := NewGeoState(dataset.path)
+		if err != nil {
+			b.Fatalf("建立地理編碼器失敗: %v", err)
+		}
+
+		// 測試不同位置的效能
+		for _, tc := range benchmarkLocations {
+			b.Run(dataset.name+"/"+tc.name, func(b *testing.B) {
+				// 解析度較低的資料可能找不到小島，查不到也是一次完整的查詢
+				for i := 0; i < b.N; i++ {
+					_, _ = geocoder.GetLocationFromGPS(tc.lat, tc.lon)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkGetLocationFromGPSRandom 全球隨機座標（包含海上查不到的位置）的平均查詢時間
+func BenchmarkGetLocationFromGPSRandom(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	points := make([][2]float64, 1024)
+	for i := range points {
+		points[i] = [2]float64{r.Float64()*180 - 90, r.Float64()*360 - 180}
+	}
+
+	for _, dataset := range benchmarkDatasets() {
+		geocoder, err := NewGeoState(dataset.path)
+		if err != nil {
+			b.Fatalf("建立地理編碼器失敗: %v", err)
+		}
+		b.Run(dataset.name+"/index", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				_, _ = geocoder.GetLocationFromGPS(p[0], p[1])
+			}
+		})
+		// 不使用索引逐一掃描，作為比較基準
+		b.Run(dataset.name+"/scan", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p := points[i%len(points)]
+				scanFeature(geocoder.collection, p[0], p[1])
+			}
+		})
+	}
+}
+
 func BenchmarkGetLocationFromGPSWithPprof(b *testing.B) {
 	// 建立 CPU profile
 	cpuFile, err := os.Create("cpu.prof")
@@ -157,36 +173,17 @@ func BenchmarkGetLocationFromGPSWithPprof(b *testing.B) {
 	defer memFile.Close()
 	defer pprof.WriteHeapProfile(memFile)
 
-	testJSONPath := "/Users/kimi/go/src/photo-sorter/geodata/states.geojson"
-
-	geocoder, err := NewGeocoder(GeoStateType, map[string]interface{}{
-		"json_path": testJSONPath,
-	})
+	datasets := benchmarkDatasets()
+	geocoder, err := NewGeoState(datasets[len(datasets)-1].path)
 	if err != nil {
 		b.Fatalf("建立地理編碼器失敗: %v", err)
 	}
 
 	// 測試不同位置的效能
-	testCases := []struct {
-		name string
-		lat  float64
-		lon  float64
-	}{
-		{"台北", 25.0330, 121.5654},
-		{"澎湖", 23.5494003, 119.5890471},
-		{"東京", 35.6895, 139.6917},
-		{"紐約", 40.7128, -74.0060},
-		{"倫敦", 51.5074, -0.1278},
-	}
-
-	for _, tc := range testCases {
+	for _, tc := range benchmarkLocations {
 		b.Run(tc.name, func(b *testing.B) {
-			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				_, err := geocoder.GetLocationFromGPS(tc.lat, tc.lon)
-				if err != nil {
-					b.Fatalf("取得位置失敗: %v", err)
-				}
+				_, _ = geocoder.GetLocationFromGPS(tc.lat, tc.lon)
 			}
 		})
 	}
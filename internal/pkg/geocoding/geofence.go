@@ -0,0 +1,84 @@
+package geocoding
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GeoFence 使用者自訂的地點範圍，可以是圓形或 GeoJSON 多邊形
+type GeoFence struct {
+	Name string
+
+	lat, lon   float64
+	radiusKM   float64
+	collection *GeoJSONCollection
+}
+
+// NewCircleFence 建立以座標為圓心、半徑 radiusKM 公里的圓形範圍
+func NewCircleFence(name string, lat, lon, radiusKM float64) (*GeoFence, error) {
+	if name == "" {
+		return nil, errors.New("地點名稱不可為空")
+	}
+	if radiusKM <= 0 {
+		return nil, fmt.Errorf("地點 %s 的半徑必須大於 0", name)
+	}
+	return &GeoFence{Name: name, lat: lat, lon: lon, radiusKM: radiusKM}, nil
+}
+
+// NewPolygonFence 建立 GeoJSON 檔案中所有多邊形組成的範圍
+func NewPolygonFence(name, jsonPath string) (*GeoFence, error) {
+	if name == "" {
+		return nil, errors.New("地點名稱不可為空")
+	}
+	collection, err := loadGeoJSONCollection(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("載入地點 %s 的 GeoJSON 失敗: %w", name, err)
+	}
+	return &GeoFence{Name: name, collection: collection}, nil
+}
+
+// Contains 座標是否在範圍內
+func (f *GeoFence) Contains(lat, lon float64) bool {
+	if f.collection != nil {
+		return f.collection.findFeature(lat, lon) != nil
+	}
+	return DistanceKM(f.lat, f.lon, lat, lon) <= f.radiusKM
+}
+
+// GeoFenceGeocoder 先比對使用者自訂的地點，再交給 fallback 查詢國家與城市
+type GeoFenceGeocoder struct {
+	fences   []*GeoFence
+	fallback Geocoder
+}
+
+// NewGeoFenceGeocoder 建立自訂地點的地理編碼器，fences 依序比對，fallback 可為 nil
+func NewGeoFenceGeocoder(fences []*GeoFence, fallback Geocoder) *GeoFenceGeocoder {
+	return &GeoFenceGeocoder{fences: fences, fallback: fallback}
+}
+
+// GetLocationFromGPS 取得座標的國家與城市，落在自訂地點內時 Place 為第一個符合的地點名稱
+func (g *GeoFenceGeocoder) GetLocationFromGPS(lat, lon float64) (*CountryCity, error) {
+	var place string
+	for _, fence := range g.fences {
+		if fence.Contains(lat, lon) {
+			place = fence.Name
+			break
+		}
+	}
+
+	var location *CountryCity
+	err := errors.New("location not found")
+	if g.fallback != nil {
+		location, err = g.fallback.GetLocationFromGPS(lat, lon)
+	}
+	if err != nil || location == nil {
+		if place == "" {
+			return nil, err
+		}
+		return &CountryCity{Place: place}, nil
+	}
+
+	result := *location
+	result.Place = place
+	return &result, nil
+}
@@ -0,0 +1,77 @@
+package geocoding
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPlaceJSON 以矩形模擬墾丁的範圍
+const testPlaceJSON = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"name": "Kenting"}, "geometry": {"type": "Polygon",
+			"coordinates": [[[120.7, 21.9], [120.9, 21.9], [120.9, 22.1], [120.7, 22.1], [120.7, 21.9]]]}}
+	]
+}`
+
+// taiwanGeocoder 台灣範圍內回傳 TWN，其他位置查不到
+type taiwanGeocoder struct{}
+
+func (taiwanGeocoder) GetLocationFromGPS(lat, lon float64) (*CountryCity, error) {
+	if lat < 21 || lat > 26 || lon < 119 || lon > 123 {
+		return nil, errors.New("location not found")
+	}
+	return &CountryCity{Country: "TWN", City: "Taipei"}, nil
+}
+
+func TestGeoFenceGeocoder(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "kenting.geojson")
+	if err := os.WriteFile(jsonPath, []byte(testPlaceJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kenting, err := NewPolygonFence("Kenting beach", jsonPath)
+	if err != nil {
+		t.Fatalf("建立多邊形範圍失敗: %v", err)
+	}
+	office, err := NewCircleFence("Office", 25.0330, 121.5654, 0.5)
+	if err != nil {
+		t.Fatalf("建立圓形範圍失敗: %v", err)
+	}
+	grandma, err := NewCircleFence("Grandma's house", 35.6762, 139.6503, 1)
+	if err != nil {
+		t.Fatalf("建立圓形範圍失敗: %v", err)
+	}
+	geocoder := NewGeoFenceGeocoder([]*GeoFence{office, kenting, grandma}, taiwanGeocoder{})
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected *CountryCity
+	}{
+		{"圓形範圍內", 25.0340, 121.5660, &CountryCity{Country: "TWN", City: "Taipei", Place: "Office"}},
+		{"圓形範圍外", 25.0500, 121.5654, &CountryCity{Country: "TWN", City: "Taipei"}},
+		{"多邊形範圍內", 22.0, 120.8, &CountryCity{Country: "TWN", City: "Taipei", Place: "Kenting beach"}},
+		{"只有自訂地點", 35.6770, 139.6510, &CountryCity{Place: "Grandma's house"}},
+		{"都查不到", 0, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geocoder.GetLocationFromGPS(tt.lat, tt.lon)
+			if tt.expected == nil {
+				if err == nil {
+					t.Errorf("應查不到地點，得到 %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("查詢地點失敗: %v", err)
+			}
+			if *got != *tt.expected {
+				t.Errorf("地點不符: 期望 %+v，得到 %+v", *tt.expected, *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package geocoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// bbox 外接矩形，經緯度的最小值與最大值
+type bbox struct {
+	minLon, minLat, maxLon, maxLat float64
+}
+
+// emptyBBox 不包含任何點的外接矩形，用於逐步擴大
+func emptyBBox() bbox {
+	return bbox{minLon: math.Inf(1), minLat: math.Inf(1), maxLon: math.Inf(-1), maxLat: math.Inf(-1)}
+}
+
+// contains 座標是否在外接矩形內（含邊界），跨越換日線的矩形經度可能超出 ±180
+func (b bbox) contains(lat, lon float64) bool {
+	if lat < b.minLat || lat > b.maxLat {
+		return false
+	}
+	for _, l := range wrapLongitudes(lon) {
+		if l >= b.minLon && l <= b.maxLon {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapped 將外接矩形切成經度在 ±180 內的部分，跨越換日線時會有兩個部分
+func (b bbox) wrapped() []bbox {
+	parts := []bbox{b}
+	if b.maxLon > 180 {
+		parts = append(parts, bbox{minLon: b.minLon - 360, minLat: b.minLat, maxLon: b.maxLon - 360, maxLat: b.maxLat})
+	}
+	if b.minLon < -180 {
+		parts = append(parts, bbox{minLon: b.minLon + 360, minLat: b.minLat, maxLon: b.maxLon + 360, maxLat: b.maxLat})
+	}
+	return parts
+}
+
+// wrapLongitudes 同一個經度在 ±360 範圍內的表示方式，用於比對跨越換日線的多邊形
+func wrapLongitudes(lon float64) [3]float64 {
+	return [3]float64{lon, lon - 360, lon + 360}
+}
+
+// extend 擴大外接矩形以包含 o
+func (b *bbox) extend(o bbox) {
+	b.minLon, b.minLat = math.Min(b.minLon, o.minLon), math.Min(b.minLat, o.minLat)
+	b.maxLon, b.maxLat = math.Max(b.maxLon, o.maxLon), math.Max(b.maxLat, o.maxLat)
+}
+
+// isEmpty 是否不包含任何點
+func (b bbox) isEmpty() bool {
+	return b.minLon > b.maxLon || b.minLat > b.maxLat
+}
+
+// ring 多邊形的一個環，GeoJSON 中的座標順序是 [經度, 緯度]
+type ring struct {
+	points [][]float64
+	bbox   bbox
+}
+
+// newRing 建立環並計算外接矩形，座標少於兩個值時回傳錯誤。
+// 相鄰兩點的經度相差超過 180 度表示跨越換日線（例如 179 到 -179），後面的點會加減 360 讓環保持連續；
+// 調整後頭尾經度不一致表示環繞著極點（例如南極洲），這種環維持原本的座標
+func newRing(points [][]float64) (ring, error) {
+	unwrapped := make([][]float64, len(points))
+	for i, p := range points {
+		if len(p) < 2 {
+			return ring{}, fmt.Errorf("無效的座標 %v", p)
+		}
+		lon := p[0]
+		if i > 0 {
+			prev := unwrapped[i-1][0]
+			for lon-prev > 180 {
+				lon -= 360
+			}
+			for prev-lon > 180 {
+				lon += 360
+			}
+		}
+		unwrapped[i] = []float64{lon, p[1]}
+	}
+	if n := len(unwrapped); n > 0 && math.Abs(unwrapped[n-1][0]-unwrapped[0][0]) > 180 {
+		unwrapped = points
+	}
+
+	r := ring{points: unwrapped, bbox: emptyBBox()}
+	for _, p := range unwrapped {
+		r.bbox.extend(bbox{minLon: p[0], minLat: p[1], maxLon: p[0], maxLat: p[1]})
+	}
+	return r, nil
+}
+
+// contains 先以外接矩形過濾，再以射線法判斷，跨越換日線的環以加減 360 的經度比對
+func (r ring) contains(lat, lon float64) bool {
+	if lat < r.bbox.minLat || lat > r.bbox.maxLat {
+		return false
+	}
+	for _, l := range wrapLongitudes(lon) {
+		if l >= r.bbox.minLon && l <= r.bbox.maxLon && isPointInPolygon(lat, l, r.points) {
+			return true
+		}
+	}
+	return false
+}
+
+// polygon 多邊形，第一個環為外環，其餘為洞
+type polygon []ring
+
+// contains 座標是否在多邊形的外環內且不在任何洞內，例如被南非包圍的賴索托不屬於南非
+func (p polygon) contains(lat, lon float64) bool {
+	if len(p) == 0 || !p[0].contains(lat, lon) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if hole.contains(lat, lon) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeGeometry 將 Polygon 或 MultiPolygon 的座標解析成多邊形，其他類型沒有多邊形
+func decodeGeometry(geometryType string, coordinates json.RawMessage) ([]polygon, error) {
+	var raw [][][][]float64
+	switch geometryType {
+	case "Polygon":
+		var single [][][]float64
+		if err := json.Unmarshal(coordinates, &single); err != nil {
+			return nil, err
+		}
+		raw = [][][][]float64{single}
+	case "MultiPolygon":
+		if err := json.Unmarshal(coordinates, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	polygons := make([]polygon, 0, len(raw))
+	for _, rings := range raw {
+		// 沒有外環的多邊形不可能包含任何點
+		if len(rings) == 0 || len(rings[0]) == 0 {
+			continue
+		}
+		p := make(polygon, 0, len(rings))
+		for _, points := range rings {
+			r, err := newRing(points)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, r)
+		}
+		polygons = append(polygons, p)
+	}
+	return polygons, nil
+}
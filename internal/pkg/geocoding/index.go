@@ -0,0 +1,55 @@
+package geocoding
+
+import "math"
+
+// gridCellDegrees 空間索引每個格子的邊長（度）
+const gridCellDegrees = 1.0
+
+// gridIndex 將經緯度切成固定大小格子的空間索引，每個格子記錄外接矩形與格子重疊的 feature，
+// 同一格中的 feature 依原本的順序排列，查詢時仍回傳第一個符合的 feature
+type gridIndex struct {
+	cols, rows int
+	cells      [][]int32
+}
+
+// newGridIndex 依 feature 的外接矩形建立索引，沒有多邊形的 feature 不加入
+func newGridIndex(features []GeoJSONFeature) *gridIndex {
+	g := &gridIndex{
+		cols: int(math.Ceil(360 / gridCellDegrees)),
+		rows: int(math.Ceil(180 / gridCellDegrees)),
+	}
+	g.cells = make([][]int32, g.cols*g.rows)
+
+	for i := range features {
+		if features[i].bbox.isEmpty() {
+			continue
+		}
+		// 跨越換日線的 feature 在兩端的格子都要加入
+		for _, b := range features[i].bbox.wrapped() {
+			minCol, minRow := g.cell(b.minLat, b.minLon)
+			maxCol, maxRow := g.cell(b.maxLat, b.maxLon)
+			for row := minRow; row <= maxRow; row++ {
+				for col := minCol; col <= maxCol; col++ {
+					idx := row*g.cols + col
+					if cell := g.cells[idx]; len(cell) == 0 || cell[len(cell)-1] != int32(i) {
+						g.cells[idx] = append(cell, int32(i))
+					}
+				}
+			}
+		}
+	}
+	return g
+}
+
+// cell 座標所在的格子，超出範圍的座標歸到最邊緣的格子
+func (g *gridIndex) cell(lat, lon float64) (col, row int) {
+	col = int(math.Floor((lon + 180) / gridCellDegrees))
+	row = int(math.Floor((lat + 90) / gridCellDegrees))
+	return min(max(col, 0), g.cols-1), min(max(row, 0), g.rows-1)
+}
+
+// candidates 外接矩形可能包含座標的 feature 索引
+func (g *gridIndex) candidates(lat, lon float64) []int32 {
+	col, row := g.cell(lat, lon)
+	return g.cells[row*g.cols+col]
+}
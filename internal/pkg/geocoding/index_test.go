@@ -0,0 +1,59 @@
+package geocoding
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// countriesJSONPath 套件內附的國家邊界資料
+const countriesJSONPath = "countries.geo.json"
+
+// scanFeature 不使用空間索引，逐一檢查每個 feature
+func scanFeature(c *GeoJSONCollection, lat, lon float64) *GeoJSONFeature {
+	for i := range c.Features {
+		if c.Features[i].contains(lat, lon) {
+			return &c.Features[i]
+		}
+	}
+	return nil
+}
+
+func TestFindFeatureIndex(t *testing.T) {
+	collection, err := loadGeoJSONCollection(countriesJSONPath)
+	if err != nil {
+		t.Fatalf("載入 GeoJSON 失敗: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected string
+	}{
+		{"台北", 25.0330, 121.5654, "TWN"},
+		{"東京", 35.6895, 139.6917, "JPN"},
+		{"紐約", 40.7128, -74.0060, "USA"},
+		{"雪梨", -33.8688, 151.2093, "AUS"},
+		{"海上", 0, 0, ""},
+		{"超出範圍", 95, 200, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if feature := collection.findFeature(tt.lat, tt.lon); feature != nil {
+				got = feature.ID
+			}
+			if got != tt.expected {
+				t.Errorf("findFeature(%v, %v) = %q, want %q", tt.lat, tt.lon, got, tt.expected)
+			}
+		})
+	}
+
+	// 隨機座標的結果需與逐一掃描相同
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		lat, lon := r.Float64()*180-90, r.Float64()*360-180
+		if got, want := collection.findFeature(lat, lon), scanFeature(collection, lat, lon); got != want {
+			t.Fatalf("findFeature(%v, %v) 與逐一掃描的結果不同", lat, lon)
+		}
+	}
+}
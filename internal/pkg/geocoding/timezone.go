@@ -0,0 +1,53 @@
+package geocoding
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	// 內嵌 IANA 時區資料庫，沒有系統時區資料的環境也能離線使用
+	_ "time/tzdata"
+)
+
+// TimeZoneGeocoder 依 GPS 座標查詢 IANA 時區名稱
+type TimeZoneGeocoder interface {
+	GetTimeZoneFromGPS(lat, lon float64) (string, error)
+}
+
+// GeoTimeZone 使用時區邊界 GeoJSON（例如 timezone-boundary-builder 的 timezones.geojson）
+// 的離線時區查詢器，每個 feature 的 properties.tzid 為 IANA 時區名稱
+type GeoTimeZone struct {
+	jsonPath   string
+	collection *GeoJSONCollection
+}
+
+// NewGeoTimeZone 建立時區查詢器並載入時區邊界資料
+func NewGeoTimeZone(jsonPath string) (*GeoTimeZone, error) {
+	collection, err := loadGeoJSONCollection(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("載入時區 GeoJSON 失敗: %w", err)
+	}
+	return &GeoTimeZone{jsonPath: jsonPath, collection: collection}, nil
+}
+
+// GetTimeZoneFromGPS 取得座標所在的 IANA 時區名稱
+func (g *GeoTimeZone) GetTimeZoneFromGPS(lat, lon float64) (string, error) {
+	feature := g.collection.findFeature(lat, lon)
+	if feature == nil || feature.Properties.TZID == "" {
+		return "", errors.New("time zone not found")
+	}
+	return feature.Properties.TZID, nil
+}
+
+// LoadLocationFromGPS 取得座標所在的時區
+func LoadLocationFromGPS(g TimeZoneGeocoder, lat, lon float64) (*time.Location, error) {
+	name, err := g.GetTimeZoneFromGPS(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("載入時區 %s 失敗: %v", name, err)
+	}
+	return loc, nil
+}
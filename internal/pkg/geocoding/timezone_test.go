@@ -0,0 +1,58 @@
+package geocoding
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testTimeZoneJSON 以兩個矩形模擬時區邊界資料
+const testTimeZoneJSON = `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"tzid": "Asia/Taipei"}, "geometry": {"type": "Polygon",
+			"coordinates": [[[119, 21], [123, 21], [123, 26], [119, 26], [119, 21]]]}},
+		{"type": "Feature", "properties": {"tzid": "America/Los_Angeles"}, "geometry": {"type": "MultiPolygon",
+			"coordinates": [[[[-125, 32], [-114, 32], [-114, 42], [-125, 42], [-125, 32]]]]}}
+	]
+}`
+
+func TestGeoTimeZone(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "timezones.geojson")
+	if err := os.WriteFile(jsonPath, []byte(testTimeZoneJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	geocoder, err := NewGeoTimeZone(jsonPath)
+	if err != nil {
+		t.Fatalf("建立時區查詢器失敗: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat      float64
+		lon      float64
+		expected string
+	}{
+		{name: "台北", lat: 25.0330, lon: 121.5654, expected: "Asia/Taipei"},
+		{name: "舊金山", lat: 37.7749, lon: -122.4194, expected: "America/Los_Angeles"},
+		{name: "海上", lat: 0, lon: 0, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := LoadLocationFromGPS(geocoder, tt.lat, tt.lon)
+			if tt.expected == "" {
+				if err == nil {
+					t.Errorf("應找不到時區，得到 %s", loc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("查詢時區失敗: %v", err)
+			}
+			if loc.String() != tt.expected {
+				t.Errorf("時區不符: 期望 %s，得到 %s", tt.expected, loc)
+			}
+		})
+	}
+}
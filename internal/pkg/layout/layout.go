@@ -0,0 +1,253 @@
+package layout
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// DefaultPathTemplate 預設的目錄結構：日期[-國家-城市]/裝置/原始檔名
+const DefaultPathTemplate = `{{.Date | default "unknown_date"}}{{if .Country}}-{{.Country}}-{{.City}}{{end}}/{{.Device | default "unknown_device"}}/{{.Name}}{{.Ext}}`
+
+// DefaultEventPathTemplate 啟用事件分群時預設的目錄結構：事件/裝置/原始檔名
+const DefaultEventPathTemplate = `{{.Event | default "unknown_date"}}/{{.Device | default "unknown_device"}}/{{.Name}}{{.Ext}}`
+
+// Fields 路徑樣板可以使用的欄位，沒有資料的欄位為空字串
+type Fields struct {
+	Year   string // 拍攝時間的年，例如 2024
+	Month  string // 月，補零，例如 05
+	Day    string // 日，補零
+	Hour   string // 時，補零
+	Minute string // 分，補零
+	Second string // 秒，補零
+	SubSec string // 毫秒，三位數，沒有毫秒資訊時為 000
+
+	Device string // 只保留英數字與底線的相機型號，例如 iPhone_15_Pro
+	Make   string // 相機廠牌
+	Model  string // 相機型號
+	Lens   string // 鏡頭型號
+
+	Country string // 國家代碼，例如 TWN
+	Region  string // 地區
+	City    string // 城市，空白換成底線
+	Place   string // 自訂地點名稱，例如 Office，不在任何自訂地點內時為空
+
+	Kind string // 媒體類型：photo、video、raw
+	Dir  string // 原始檔案相對於來源資料夾的目錄，位於來源資料夾根目錄時為空
+	Name string // 原始檔名，不含副檔名
+	Ext  string // 原始副檔名，含 "."
+	Seq  string // 連拍序號，例如 _003，不是連拍時為空
+
+	Event string // 事件名稱，例如 2024-05-03_to_2024-05-09-JPN-Tokyo，沒有啟用事件分群時為空
+	Trip  string // 旅行名稱，例如 2024-05-Trip-JPN，不在旅行中時為空
+
+	path       string
+	captured   time.Time
+	dateFormat string
+	hash       string
+}
+
+// NewFields 建立原始檔案的欄位，captured 為零值表示沒有拍攝時間，dateFormat 為 Date 預設的格式
+func NewFields(path string, captured time.Time, dateFormat string) *Fields {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	f := &Fields{
+		Name:       strings.TrimSuffix(base, ext),
+		Ext:        ext,
+		path:       path,
+		captured:   captured,
+		dateFormat: dateFormat,
+	}
+	if !captured.IsZero() {
+		f.Year = captured.Format("2006")
+		f.Month = captured.Format("01")
+		f.Day = captured.Format("02")
+		f.Hour = captured.Format("15")
+		f.Minute = captured.Format("04")
+		f.Second = captured.Format("05")
+		f.SubSec = fmt.Sprintf("%03d", captured.Nanosecond()/int(time.Millisecond))
+	}
+	return f
+}
+
+// Date 以 Go 時間格式輸出拍攝時間，沒有指定格式時使用 date_format，沒有拍攝時間時為空
+func (f *Fields) Date(format ...string) string {
+	if f.captured.IsZero() {
+		return ""
+	}
+	layout := f.dateFormat
+	if len(format) > 0 {
+		layout = format[0]
+	}
+	return f.captured.Format(layout)
+}
+
+// Hash 檔案內容 SHA-256 的前 n 個十六進位字元，只在樣板用到時才計算
+func (f *Fields) Hash(n int) (string, error) {
+	if f.hash == "" {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+		f.hash = hex.EncodeToString(h.Sum(nil))
+	}
+	if n <= 0 || n > len(f.hash) {
+		n = len(f.hash)
+	}
+	return f.hash[:n], nil
+}
+
+// funcs 樣板可以使用的函式
+var funcs = template.FuncMap{
+	// default 值為空時使用預設值，例如 {{.City | default "unknown"}}
+	"default": func(def, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return def
+		}
+		return value
+	},
+	// sanitize 空白換成底線，只保留文字、數字、"-"、"_" 與 "."
+	"sanitize": Sanitize,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+}
+
+// Sanitize 空白換成底線，只保留文字、數字、"-"、"_" 與 "."
+func Sanitize(s string) string {
+	s = strings.Join(strings.Fields(s), "_")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// Template 解析後的路徑樣板
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse 解析路徑樣板，並以範例欄位執行一次，確認使用的欄位與函式都存在
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("path").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析路徑樣板失敗: %v", err)
+	}
+	t := &Template{tmpl: tmpl}
+
+	if _, err := t.Execute(sampleFields()); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// sampleFields 檢查樣板用的範例欄位，所有欄位都有值
+func sampleFields() *Fields {
+	sample := NewFields("IMG_0001.JPG", time.Date(2024, 5, 3, 10, 20, 30, 0, time.UTC), "2006-01")
+	sample.Device, sample.Make, sample.Model, sample.Lens = "Camera", "Maker", "Camera", "Lens"
+	sample.Country, sample.Region, sample.City, sample.Place = "TWN", "Region", "City", "Place"
+	sample.Kind, sample.Dir, sample.Seq = "photo", "album", "_001"
+	sample.Event, sample.Trip = "2024-05-03_to_2024-05-09-TWN-City", "2024-05-Trip-TWN"
+	sample.hash = strings.Repeat("0", sha256.Size*2)
+	return sample
+}
+
+// MustParse 與 Parse 相同，但解析失敗時 panic，用於內建的樣板
+func MustParse(text string) *Template {
+	t, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Default 預設目錄結構的樣板
+var Default = MustParse(DefaultPathTemplate)
+
+// DefaultEvent 啟用事件分群時預設目錄結構的樣板
+var DefaultEvent = MustParse(DefaultEventPathTemplate)
+
+// Execute 產生相對於目標資料夾的路徑，欄位中的路徑分隔字元會被替換，結果不能跳出目標資料夾
+func (t *Template) Execute(f *Fields) (string, error) {
+	out, err := render(t.tmpl, f)
+	if err != nil {
+		return "", fmt.Errorf("執行路徑樣板失敗: %v", err)
+	}
+
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(out)))
+	switch {
+	case rel == "." || strings.HasSuffix(out, "/"):
+		return "", errors.New("路徑樣板沒有產生檔名")
+	case filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)):
+		return "", fmt.Errorf("路徑樣板產生的路徑超出目標資料夾: %s", rel)
+	}
+	return rel, nil
+}
+
+// NameTemplate 解析後的檔名樣板，產生不含副檔名的檔名
+type NameTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseName 解析檔名樣板，並以範例欄位執行一次，確認使用的欄位與函式都存在
+func ParseName(text string) (*NameTemplate, error) {
+	tmpl, err := template.New("name").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析檔名樣板失敗: %v", err)
+	}
+	t := &NameTemplate{tmpl: tmpl}
+	if _, err := t.Execute(sampleFields()); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Execute 產生不含副檔名的檔名，樣板輸出中的路徑分隔字元會被替換成底線
+func (t *NameTemplate) Execute(f *Fields) (string, error) {
+	out, err := render(t.tmpl, f)
+	if err != nil {
+		return "", fmt.Errorf("執行檔名樣板失敗: %v", err)
+	}
+	name := strings.TrimSpace(strings.NewReplacer("/", "_", `\`, "_").Replace(out))
+	if name == "" || name == "." || name == ".." {
+		return "", errors.New("檔名樣板沒有產生檔名")
+	}
+	return name, nil
+}
+
+// render 以替換過路徑分隔字元的欄位執行樣板，並保留計算過的雜湊值
+func render(tmpl *template.Template, f *Fields) (string, error) {
+	safe := *f
+	for _, field := range []*string{
+		&safe.Year, &safe.Month, &safe.Day, &safe.Hour, &safe.Minute, &safe.Second, &safe.SubSec,
+		&safe.Device, &safe.Make, &safe.Model, &safe.Lens,
+		&safe.Country, &safe.Region, &safe.City, &safe.Place,
+		&safe.Kind, &safe.Name, &safe.Ext, &safe.Seq, &safe.Event, &safe.Trip,
+	} {
+		*field = strings.NewReplacer("/", "_", `\`, "_").Replace(*field)
+	}
+	// Dir 本身就是路徑，只需要正規化
+	safe.Dir = filepath.ToSlash(filepath.Clean("/" + safe.Dir))[1:]
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, &safe); err != nil {
+		return "", err
+	}
+	f.hash = safe.hash
+	return b.String(), nil
+}
@@ -0,0 +1,126 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecute(t *testing.T) {
+	captured := time.Date(2024, 5, 3, 10, 20, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		fields   func() *Fields
+		expected string
+	}{
+		{
+			name:     "預設目錄結構",
+			template: DefaultPathTemplate,
+			fields: func() *Fields {
+				f := NewFields("/src/IMG_0001.JPG", captured, "2006-01")
+				f.Device, f.Country, f.City = "iPhone_15", "TWN", "New_Taipei"
+				return f
+			},
+			expected: "2024-05-TWN-New_Taipei/iPhone_15/IMG_0001.JPG",
+		},
+		{
+			name:     "預設目錄結構沒有日期與裝置",
+			template: DefaultPathTemplate,
+			fields: func() *Fields {
+				return NewFields("/src/IMG_0001.JPG", time.Time{}, "2006-01")
+			},
+			expected: "unknown_date/unknown_device/IMG_0001.JPG",
+		},
+		{
+			name:     "日期各部分、預設值與 sanitize",
+			template: `{{.Year}}/{{.Month}}/{{.Country | default "unknown"}}/{{.Lens | sanitize}}/{{.Name}}{{.Ext | lower}}`,
+			fields: func() *Fields {
+				f := NewFields("/src/IMG_0001.JPG", captured, "2006-01")
+				f.Lens = "FE 24-70mm F2.8 GM II"
+				return f
+			},
+			expected: "2024/05/unknown/FE_24-70mm_F2.8_GM_II/IMG_0001.jpg",
+		},
+		{
+			name:     "欄位中的路徑分隔字元不會建立資料夾",
+			template: `{{.Model}}/{{.Dir}}/{{.Name}}{{.Ext}}`,
+			fields: func() *Fields {
+				f := NewFields("/src/a/b/IMG_0001.JPG", captured, "2006-01")
+				f.Model, f.Dir = "EOS 5D/Mark IV", "a/../../b"
+				return f
+			},
+			expected: "EOS 5D_Mark IV/b/IMG_0001.JPG",
+		},
+		{
+			name:     "自訂日期格式",
+			template: `{{.Kind}}/{{.Date "2006/01-02"}}/{{.Name}}{{.Ext}}`,
+			fields: func() *Fields {
+				f := NewFields("/src/VID_0001.MP4", captured, "2006-01")
+				f.Kind = "video"
+				return f
+			},
+			expected: "video/2024/05-03/VID_0001.MP4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatalf("解析樣板失敗: %v", err)
+			}
+			got, err := tmpl.Execute(tt.fields())
+			if err != nil {
+				t.Fatalf("執行樣板失敗: %v", err)
+			}
+			if expected := filepath.FromSlash(tt.expected); got != expected {
+				t.Errorf("路徑不符: 期望 %s，得到 %s", expected, got)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"語法錯誤", `{{.Year`},
+		{"不存在的欄位", `{{.Camera}}/{{.Name}}`},
+		{"不存在的函式", `{{.Name | title}}`},
+		{"沒有檔名", `{{.Year}}/`},
+		{"超出目標資料夾", `../{{.Name}}{{.Ext}}`},
+		{"絕對路徑", `/tmp/{{.Name}}{{.Ext}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.template); err == nil {
+				t.Errorf("樣板 %q 應該無效", tt.template)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	if err := os.WriteFile(path, []byte("photo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := Parse(`{{.Hash 8}}/{{.Name}}{{.Ext}}`)
+	if err != nil {
+		t.Fatalf("解析樣板失敗: %v", err)
+	}
+
+	got, err := tmpl.Execute(NewFields(path, time.Time{}, "2006-01"))
+	if err != nil {
+		t.Fatalf("執行樣板失敗: %v", err)
+	}
+	// sha256("photo")
+	if expected := filepath.Join("55c64d0f", "IMG_0001.JPG"); got != expected {
+		t.Errorf("路徑不符: 期望 %s，得到 %s", expected, got)
+	}
+}